@@ -0,0 +1,275 @@
+package sox
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ChannelStats holds the figures of the sox stats effect for one channel, or for
+// all channels together. Levels are relative to full scale; figures sox does not
+// report (such as the overall crest factor of multi-channel audio) are NaN.
+type ChannelStats struct {
+	DCOffset    float64 // Mean sample value
+	MinLevel    float64 // Lowest sample value, -1 to 1
+	MaxLevel    float64 // Highest sample value, -1 to 1
+	PeakLevel   float64 // Peak level in dBFS
+	RMSLevel    float64 // RMS level in dBFS, -Inf for digital silence
+	RMSPeak     float64 // Highest RMS level of a window, in dBFS
+	RMSTrough   float64 // Lowest RMS level of a window, in dBFS
+	CrestFactor float64 // Peak to RMS ratio
+	FlatFactor  float64 // Flat factor in dB: consecutive samples at the peak level, a clipping indicator
+	PeakCount   int64   // Number of times the peak level is reached; approximate above 1000
+	Clipped     int64   // Samples at full scale: PeakCount when the peak reaches 0 dBFS, else 0
+	BitsUsed    int     // Bits actually used by the samples
+	BitsTotal   int     // Bits available per sample
+}
+
+// Stats holds the output of the sox stats effect
+type Stats struct {
+	Overall  ChannelStats   // Figures across all channels
+	Channels []ChannelStats // Figures per channel
+	Length   time.Duration  // Audio length
+	ScaleMax float64        // Full scale sample value used for the levels
+	Window   time.Duration  // RMS window length
+}
+
+// statsFields maps the row labels of the stats effect to ChannelStats fields
+var statsFields = map[string]func(*ChannelStats, string){
+	"DC offset":    func(c *ChannelStats, v string) { c.DCOffset = parseStatsFloat(v) },
+	"Min level":    func(c *ChannelStats, v string) { c.MinLevel = parseStatsFloat(v) },
+	"Max level":    func(c *ChannelStats, v string) { c.MaxLevel = parseStatsFloat(v) },
+	"Pk lev dB":    func(c *ChannelStats, v string) { c.PeakLevel = parseStatsFloat(v) },
+	"RMS lev dB":   func(c *ChannelStats, v string) { c.RMSLevel = parseStatsFloat(v) },
+	"RMS Pk dB":    func(c *ChannelStats, v string) { c.RMSPeak = parseStatsFloat(v) },
+	"RMS Tr dB":    func(c *ChannelStats, v string) { c.RMSTrough = parseStatsFloat(v) },
+	"Crest factor": func(c *ChannelStats, v string) { c.CrestFactor = parseStatsFloat(v) },
+	"Flat factor":  func(c *ChannelStats, v string) { c.FlatFactor = parseStatsFloat(v) },
+	"Pk count":     func(c *ChannelStats, v string) { c.PeakCount = int64(parseStatsFloat(v)) },
+	"Bit-depth": func(c *ChannelStats, v string) {
+		used, total, _ := strings.Cut(v, "/")
+		c.BitsUsed, _ = strconv.Atoi(used)
+		c.BitsTotal, _ = strconv.Atoi(total)
+	},
+}
+
+// Analyze computes signal statistics of an audio file or stream by running the
+// sox stats effect. See Task.Analyze; Analyze uses a Task with the default
+// options and format as its Input.
+//
+// Example:
+//
+//	stats, err := sox.Analyze(ctx, "call.wav", sox.AudioFormat{})
+//	if err != nil {
+//		return err
+//	}
+//	if stats.Overall.Clipped > 0 || stats.Overall.RMSLevel < -40 {
+//		log.Printf("check recording: peak %.1f dB, rms %.1f dB", stats.Overall.PeakLevel, stats.Overall.RMSLevel)
+//	}
+func Analyze(ctx context.Context, input interface{}, format AudioFormat) (*Stats, error) {
+	return New(format, format).Analyze(ctx, input)
+}
+
+// Analyze computes signal statistics of an audio file or stream in the Input
+// format, by running the sox stats effect to a null output. The input is a file
+// path or an io.Reader. Readers need an Input format sox can read from a pipe;
+// when Input.Type is empty the container is sniffed, and for paths sox detects it.
+//
+// The analysis runs Options.SoxPath, honors Options.Timeout and goes through the
+// Task circuit breaker. Options.Effects are not applied.
+//
+// Example:
+//
+//	task := sox.New(sox.PCM_RAW_8K_MONO, sox.PCM_RAW_8K_MONO)
+//	stats, err := task.Analyze(ctx, bytes.NewReader(pcm))
+//	if err != nil {
+//		return err
+//	}
+//	for i, ch := range stats.Channels {
+//		log.Printf("channel %d: rms %.1f dB, dc %.4f", i+1, ch.RMSLevel, ch.DCOffset)
+//	}
+func (c *Task) Analyze(ctx context.Context, input interface{}) (*Stats, error) {
+	inputArgs, stdin, err := c.soxInput(input)
+	if err != nil {
+		return nil, err
+	}
+
+	args := append(c.Options.BuildGlobalArgs(), inputArgs...)
+	args = append(args, "-n", "stats")
+
+	var stats *Stats
+
+	err = c.guardedCall(ctx, func(ctx context.Context) error {
+		// stats reports to stderr
+		stderr, err := c.execSox(ctx, "analysis", args, stdin, nil)
+		if err != nil {
+			return err
+		}
+
+		stats, err = parseStats(stderr)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// soxInput returns the sox arguments reading an input path or reader in the Input
+// format, and the reader to use as stdin. Readers are sniffed when Input.Type is empty.
+func (c *Task) soxInput(input interface{}) ([]string, io.Reader, error) {
+	format := c.Input
+	var target string
+	var stdin io.Reader
+
+	switch in := input.(type) {
+	case string:
+		target = in
+	case io.Reader:
+		target = "-"
+		stdin = in
+
+		if format.Type == "" {
+			sniffed, replay, err := SniffFormat(in)
+			if err != nil {
+				return nil, nil, err
+			}
+			format.Type = sniffed.Type
+			stdin = replay
+		}
+	default:
+		return nil, nil, fmt.Errorf("unsupported input type: %T", input)
+	}
+
+	if err := format.ValidateFor(RoleInput); err != nil {
+		return nil, nil, fmt.Errorf("input format: %w", err)
+	}
+
+	return append(format.BuildArgsFor(RoleInput), target), stdin, nil
+}
+
+// parseStats parses the report of the stats effect. Mono audio has a single
+// column; otherwise the first column is the overall figure, followed by one
+// column per channel under an "Overall Left Right" or "Overall Ch1 Ch2 ..." header.
+func parseStats(report []byte) (*Stats, error) {
+	stats := &Stats{Overall: newChannelStats()}
+	channels := 0
+	found := false
+
+	scanner := bufio.NewScanner(bytes.NewReader(report))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if strings.HasPrefix(line, "Overall") {
+			channels = len(strings.Fields(line)) - 1
+			for i := 0; i < channels; i++ {
+				stats.Channels = append(stats.Channels, newChannelStats())
+			}
+			continue
+		}
+
+		label, values := splitStatsLine(line)
+		if label == "" {
+			continue
+		}
+
+		switch label {
+		case "Length s":
+			stats.Length = time.Duration(parseStatsFloat(values[0]) * float64(time.Second))
+		case "Window s":
+			stats.Window = time.Duration(parseStatsFloat(values[0]) * float64(time.Second))
+		case "Scale max":
+			stats.ScaleMax = parseStatsFloat(values[0])
+		case "Num samples":
+			// Rounded to three significant figures, Length is exact
+		default:
+			set, ok := statsFields[label]
+			if !ok {
+				continue
+			}
+
+			found = true
+			set(&stats.Overall, values[0])
+			for i := 1; i < len(values) && i <= channels; i++ {
+				set(&stats.Channels[i-1], values[i])
+			}
+		}
+	}
+
+	if !found {
+		return nil, fmt.Errorf("no stats in sox output: %s", strings.TrimSpace(string(report)))
+	}
+
+	if channels == 0 {
+		stats.Channels = []ChannelStats{stats.Overall}
+	}
+
+	setClipped(&stats.Overall)
+	for i := range stats.Channels {
+		setClipped(&stats.Channels[i])
+	}
+
+	return stats, nil
+}
+
+// splitStatsLine splits a stats row into its label and values, or returns an empty
+// label for lines that are not stats rows (warnings, blank lines)
+func splitStatsLine(line string) (string, []string) {
+	fields := strings.Fields(line)
+
+	for words := 1; words <= 3 && words < len(fields); words++ {
+		label := strings.Join(fields[:words], " ")
+
+		if _, ok := statsFields[label]; ok {
+			return label, fields[words:]
+		}
+
+		switch label {
+		case "Length s", "Window s", "Scale max", "Num samples":
+			return label, fields[words:]
+		}
+	}
+
+	return "", nil
+}
+
+// newChannelStats returns stats with the figures sox may leave out set to NaN
+func newChannelStats() ChannelStats {
+	return ChannelStats{CrestFactor: math.NaN()}
+}
+
+// setClipped counts the peak samples as clipped when the peak reaches full scale
+func setClipped(c *ChannelStats) {
+	if c.PeakLevel >= 0 {
+		c.Clipped = c.PeakCount
+	}
+}
+
+// parseStatsFloat parses a stats value, which may be "-inf", "-" (not applicable)
+// or rounded with a k or M suffix, e.g. "1.23k"
+func parseStatsFloat(value string) float64 {
+	scale := 1.0
+
+	switch {
+	case strings.HasSuffix(value, "k"):
+		scale, value = 1e3, strings.TrimSuffix(value, "k")
+	case strings.HasSuffix(value, "M"):
+		scale, value = 1e6, strings.TrimSuffix(value, "M")
+	case strings.HasSuffix(value, "G"):
+		scale, value = 1e9, strings.TrimSuffix(value, "G")
+	}
+
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return math.NaN()
+	}
+
+	return f * scale
+}
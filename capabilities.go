@@ -0,0 +1,222 @@
+package sox
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrNotSupported is returned by Task.Preflight when the installed sox lacks a
+// file format or effect the Task needs
+var ErrNotSupported = errors.New("not supported by the installed sox")
+
+// Capabilities describes what an installed sox binary supports
+type Capabilities struct {
+	Path    string   // sox binary the capabilities were read from
+	Version string   // sox version, e.g. "14.4.2"
+	Formats []string // Supported file formats (-t values), sorted
+	Effects []string // Supported effects, sorted
+
+	formats map[string]bool
+	effects map[string]bool
+}
+
+// capabilitiesCache holds the capabilities of each probed sox binary by path
+var (
+	capabilitiesLock  sync.Mutex
+	capabilitiesCache = map[string]*Capabilities{}
+)
+
+// soxVersionPattern matches the version in the output of sox --version, e.g. "SoX v14.4.2"
+var soxVersionPattern = regexp.MustCompile(`v(\d+(?:\.\d+)*)`)
+
+// soxEffects lists the effects of sox 14.4. In Options.Effects, a known effect
+// name starts a new effect; other words are arguments of the preceding effect.
+var soxEffects = map[string]bool{
+	"allpass": true, "band": true, "bandpass": true, "bandreject": true, "bass": true,
+	"bend": true, "biquad": true, "chorus": true, "channels": true, "compand": true,
+	"contrast": true, "dcshift": true, "deemph": true, "delay": true, "dither": true,
+	"divide": true, "downsample": true, "earwax": true, "echo": true, "echos": true,
+	"equalizer": true, "fade": true, "fir": true, "firfit": true, "flanger": true,
+	"gain": true, "highpass": true, "hilbert": true, "ladspa": true, "loudness": true,
+	"lowpass": true, "mcompand": true, "noiseprof": true, "noisered": true, "norm": true,
+	"oops": true, "overdrive": true, "pad": true, "phaser": true, "pitch": true,
+	"rate": true, "remix": true, "repeat": true, "reverb": true, "reverse": true,
+	"riaa": true, "silence": true, "sinc": true, "spectrogram": true, "speed": true,
+	"splice": true, "stat": true, "stats": true, "stretch": true, "swap": true,
+	"synth": true, "tempo": true, "treble": true, "tremolo": true, "trim": true,
+	"upsample": true, "vad": true, "vol": true,
+}
+
+// ProbeSox reads the version, file formats and effects of the sox binary at path,
+// resolved with FindSox, from sox --version and sox -h. Results are cached per
+// binary, failures are not.
+//
+// Example:
+//
+//	caps, err := sox.ProbeSox("")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	if !caps.SupportsFormat("mp3") {
+//		log.Fatalf("sox %s at %s was built without mp3 support", caps.Version, caps.Path)
+//	}
+func ProbeSox(path string) (*Capabilities, error) {
+	path, err := FindSox(path)
+	if err != nil {
+		return nil, err
+	}
+
+	capabilitiesLock.Lock()
+	caps, ok := capabilitiesCache[path]
+	capabilitiesLock.Unlock()
+
+	if ok {
+		return caps, nil
+	}
+
+	version, err := exec.Command(path, "--version").Output()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s is not executable: %w", ErrSoxNotFound, path, err)
+	}
+
+	// Some builds exit with a non-zero status after printing the help
+	help, err := exec.Command(path, "-h").Output()
+	if err != nil && !strings.Contains(string(help), "EFFECTS:") {
+		return nil, fmt.Errorf("failed to read sox capabilities: %w", err)
+	}
+
+	caps = parseCapabilities(string(version), string(help))
+	caps.Path = path
+
+	capabilitiesLock.Lock()
+	capabilitiesCache[path] = caps
+	capabilitiesLock.Unlock()
+
+	return caps, nil
+}
+
+// parseCapabilities parses the output of sox --version and sox -h
+func parseCapabilities(version, help string) *Capabilities {
+	caps := &Capabilities{formats: map[string]bool{}, effects: map[string]bool{}}
+
+	if m := soxVersionPattern.FindStringSubmatch(version); m != nil {
+		caps.Version = m[1]
+	}
+
+	for _, line := range strings.Split(help, "\n") {
+		label, list, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+
+		switch strings.TrimSpace(label) {
+		case "AUDIO FILE FORMATS":
+			for _, name := range strings.Fields(list) {
+				caps.formats[name] = true
+			}
+		case "EFFECTS":
+			// Names are marked * (deprecated), + (experimental) or # (libsox only)
+			for _, name := range strings.Fields(list) {
+				caps.effects[strings.TrimRight(name, "*+#")] = true
+			}
+		}
+	}
+
+	caps.Formats = sortedKeys(caps.formats)
+	caps.Effects = sortedKeys(caps.effects)
+
+	return caps
+}
+
+// SupportsFormat reports whether sox can read or write the file format (a -t value)
+func (c *Capabilities) SupportsFormat(name string) bool {
+	return c.formats[strings.ToLower(name)]
+}
+
+// SupportsEffect reports whether sox has the effect
+func (c *Capabilities) SupportsEffect(name string) bool {
+	return c.effects[strings.ToLower(name)]
+}
+
+// Preflight checks that the installed sox (Options.SoxPath) supports the input and
+// output types and the effects in Options.Effects, so a Task built for a sox with
+// mp3 or an optional effect fails at startup rather than at its first conversion.
+// Empty types are skipped, except that an output path set with WithOutputPath
+// stands for its extension. Missing features are joined in one error, each
+// matching ErrNotSupported.
+//
+// Example:
+//
+//	task := sox.New(sox.AudioFormat{Type: sox.TYPE_MP3}, sox.FLAC_16K_MONO)
+//	if err := task.Preflight(); err != nil {
+//		log.Fatalf("sox cannot run this task: %v", err)
+//	}
+func (c *Task) Preflight() error {
+	caps, err := ProbeSox(c.Options.SoxPath)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+
+	output := c.outputFormat()
+	if output.Type == "" && c.outputPath != "" {
+		output.Type = typeFromExtension(c.outputPath)
+	}
+
+	types := []struct {
+		role FormatRole
+		typ  string
+	}{
+		{RoleInput, c.inputFormat().Type},
+		{RoleOutput, output.Type},
+	}
+
+	for _, t := range types {
+		if t.typ != "" && !caps.SupportsFormat(t.typ) {
+			errs = append(errs, fmt.Errorf("%w: %s type %q", ErrNotSupported, t.role, t.typ))
+		}
+	}
+
+	for _, effect := range effectNames(c.Options.Effects) {
+		if !caps.SupportsEffect(effect) {
+			errs = append(errs, fmt.Errorf("%w: effect %q", ErrNotSupported, effect))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("sox %s at %s: %w", caps.Version, caps.Path, errors.Join(errs...))
+	}
+
+	return nil
+}
+
+// effectNames returns the effect names in an effects argument list: the first
+// argument, and every argument naming a sox effect
+func effectNames(args []string) []string {
+	var names []string
+
+	for i, arg := range args {
+		if i == 0 || soxEffects[strings.ToLower(arg)] {
+			names = append(names, arg)
+		}
+	}
+
+	return names
+}
+
+// sortedKeys returns the keys of a set in order
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
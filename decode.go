@@ -0,0 +1,124 @@
+package sox
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// decodeRate is the rate inputs are decoded at when their sample rate is unknown
+const decodeRate = 16000
+
+// decodeFrames is the number of frames passed to a decode callback at a time
+const decodeFrames = 4096
+
+// decodePCM decodes an input path or reader in the Input format to 32-bit float
+// samples at the given rate and channel count, and passes them to fn in chunks of
+// whole interleaved frames. Samples are streamed, so long recordings are never
+// held in memory. The run honors Options.Timeout and the Task circuit breaker;
+// op names the operation in errors.
+func (c *Task) decodePCM(ctx context.Context, op string, input interface{}, rate, channels int, fn func(samples []float32)) error {
+	inputArgs, stdin, err := c.soxInput(input)
+	if err != nil {
+		return err
+	}
+
+	output := AudioFormat{
+		Type:       TYPE_RAW,
+		Encoding:   FLOATING_POINT,
+		BitDepth:   32,
+		Endian:     "little",
+		SampleRate: rate,
+		Channels:   channels,
+	}
+
+	args := append(c.Options.BuildGlobalArgs(), inputArgs...)
+	args = append(args, output.BuildArgsFor(RoleOutput)...)
+	args = append(args, "-")
+
+	return c.guardedCall(ctx, func(ctx context.Context) error {
+		w := &floatWriter{channels: channels, fn: fn}
+		if _, err := c.execSox(ctx, op, args, stdin, w); err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
+
+// inputLayout returns the rate and channel count to decode an input at: those of
+// the Input format, completed by probing a path. Readers without a rate are decoded
+// at decodeRate. Without perChannel the input is mixed down to one channel;
+// otherwise the channel count must be known.
+func (c *Task) inputLayout(ctx context.Context, op string, input interface{}, perChannel bool) (int, int, error) {
+	rate, channels := c.Input.SampleRate, c.Input.Channels
+
+	if path, ok := input.(string); ok && (rate == 0 || perChannel && channels == 0) {
+		info, err := c.Probe(ctx, path)
+		if err != nil {
+			return 0, 0, err
+		}
+
+		if rate == 0 {
+			rate = info.SampleRate
+		}
+		if channels == 0 {
+			channels = info.Channels
+		}
+	}
+
+	if rate == 0 {
+		rate = decodeRate
+	}
+
+	if !perChannel {
+		return rate, 1, nil
+	}
+
+	if channels == 0 {
+		if _, ok := input.(string); ok {
+			return 0, 0, fmt.Errorf("per-channel %s: unknown channel count", op)
+		}
+		return 0, 0, fmt.Errorf("per-channel %s of a reader requires Input.Channels", op)
+	}
+
+	return rate, channels, nil
+}
+
+// floatWriter decodes 32-bit float little-endian PCM and passes whole frames to fn,
+// carrying partial frames over to the next Write
+type floatWriter struct {
+	channels int
+	fn       func(samples []float32)
+	carry    []byte
+	samples  []float32
+}
+
+func (w *floatWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	frameSize := 4 * w.channels
+
+	if len(w.carry) > 0 {
+		p = append(w.carry, p...)
+		w.carry = nil
+	}
+
+	for len(p) >= frameSize {
+		frames := min(len(p)/frameSize, decodeFrames)
+
+		w.samples = w.samples[:0]
+		for i := 0; i < frames*w.channels; i++ {
+			w.samples = append(w.samples, math.Float32frombits(binary.LittleEndian.Uint32(p[4*i:])))
+		}
+		w.fn(w.samples)
+
+		p = p[frames*frameSize:]
+	}
+
+	if len(p) > 0 {
+		w.carry = append([]byte(nil), p...)
+	}
+
+	return n, nil
+}
@@ -0,0 +1,196 @@
+package sox
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// MinSoxVersion is the oldest sox release supported by the package
+const MinSoxVersion = "14.4"
+
+// ErrSoxNotFound is returned when no sox binary can be found
+var ErrSoxNotFound = errors.New("sox not found")
+
+// soxLocations are the well-known install locations tried after PATH
+var soxLocations = []string{
+	"/usr/bin/sox",
+	"/usr/local/bin/sox",
+	"/opt/homebrew/bin/sox",
+	"/opt/local/bin/sox",
+	"/snap/bin/sox",
+}
+
+// soxPaths caches resolved, version-checked sox binaries by requested path
+var (
+	soxPathsLock sync.Mutex
+	soxPaths     = map[string]string{}
+)
+
+// VersionError is returned when the sox binary is older than MinSoxVersion,
+// or its version cannot be read
+type VersionError struct {
+	Path    string // Resolved sox binary
+	Version string // Version reported by sox, empty when unreadable
+	Minimum string // Required version
+}
+
+func (e *VersionError) Error() string {
+	if e.Version == "" {
+		return fmt.Sprintf("cannot read the version of sox at %s, %s or later is required", e.Path, e.Minimum)
+	}
+
+	return fmt.Sprintf("sox %s at %s is too old, %s or later is required", e.Version, e.Path, e.Minimum)
+}
+
+// FindSox returns the absolute path of the sox binary to run. An explicit path
+// (a file path or a command name) is used as is; when empty, the SOX_PATH
+// environment variable, then PATH, then the well-known install locations
+// (/usr/bin, /usr/local/bin, Homebrew, MacPorts, snap) are tried in order.
+//
+// The binary must be sox 14.4 or later, otherwise a *VersionError is returned.
+// Results are cached per explicit path; call RefreshSox after upgrading or
+// moving sox, or changing SOX_PATH.
+//
+// Example:
+//
+//	path, err := sox.FindSox("")
+//	var versionErr *sox.VersionError
+//	switch {
+//	case errors.Is(err, sox.ErrSoxNotFound):
+//		log.Fatal("install sox or set SOX_PATH")
+//	case errors.As(err, &versionErr):
+//		log.Fatalf("upgrade sox: %v", versionErr)
+//	}
+func FindSox(explicit string) (string, error) {
+	soxPathsLock.Lock()
+	path, ok := soxPaths[explicit]
+	soxPathsLock.Unlock()
+
+	if ok {
+		return path, nil
+	}
+
+	// Run sox outside the lock so a slow binary does not hold up other callers
+	path, err := locateSox(explicit)
+	if err != nil {
+		return "", err
+	}
+
+	if err := checkSoxVersion(path); err != nil {
+		return "", err
+	}
+
+	soxPathsLock.Lock()
+	soxPaths[explicit] = path
+	soxPathsLock.Unlock()
+
+	return path, nil
+}
+
+// RefreshSox forgets the resolved sox binaries and their capabilities, so the next
+// conversion discovers sox again. Use it after upgrading sox in place.
+//
+// Example:
+//
+//	// After a package upgrade, without restarting the service
+//	sox.RefreshSox()
+//	if err := sox.CheckSoxInstalled(""); err != nil {
+//		log.Printf("sox unusable after upgrade: %v", err)
+//	}
+func RefreshSox() {
+	soxPathsLock.Lock()
+	soxPaths = map[string]string{}
+	soxPathsLock.Unlock()
+
+	capabilitiesLock.Lock()
+	capabilitiesCache = map[string]*Capabilities{}
+	capabilitiesLock.Unlock()
+}
+
+// locateSox resolves the requested sox binary to an absolute path
+func locateSox(explicit string) (string, error) {
+	if explicit != "" {
+		return resolveSox(explicit, "SoxPath")
+	}
+
+	if env := os.Getenv("SOX_PATH"); env != "" {
+		return resolveSox(env, "SOX_PATH")
+	}
+
+	if path, err := exec.LookPath("sox"); err == nil {
+		return filepath.Abs(path)
+	}
+
+	for _, path := range soxLocations {
+		if isExecutable(path) {
+			return path, nil
+		}
+	}
+
+	return "", fmt.Errorf("%w in SOX_PATH, PATH or %s", ErrSoxNotFound, strings.Join(soxLocations, ", "))
+}
+
+// resolveSox resolves a configured path or command name, named after its source in errors
+func resolveSox(path, source string) (string, error) {
+	resolved, err := exec.LookPath(path)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s %q: %w", ErrSoxNotFound, source, path, err)
+	}
+
+	return filepath.Abs(resolved)
+}
+
+// isExecutable reports whether path is an executable regular file
+func isExecutable(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular() && info.Mode().Perm()&0111 != 0
+}
+
+// checkSoxVersion runs sox --version and compares it with MinSoxVersion
+func checkSoxVersion(path string) error {
+	output, err := exec.Command(path, "--version").Output()
+	if err != nil {
+		return fmt.Errorf("%w: %s is not executable: %w", ErrSoxNotFound, path, err)
+	}
+
+	version := ""
+	if m := soxVersionPattern.FindStringSubmatch(string(output)); m != nil {
+		version = m[1]
+	}
+
+	if version == "" || compareVersions(version, MinSoxVersion) < 0 {
+		return &VersionError{Path: path, Version: version, Minimum: MinSoxVersion}
+	}
+
+	return nil
+}
+
+// compareVersions compares dotted versions numerically, missing parts counting as 0
+func compareVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+
+	for i := 0; i < max(len(as), len(bs)); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,246 @@
+package sox
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// EncoderSettings are typed output encoder settings, set with Task.WithEncoder.
+// They are rendered as output format options (the -C value, or the encoding for
+// GSM and ADPCM) and validated against the output type before sox runs.
+//
+// Implemented by FLACSettings, MP3Settings, VorbisSettings, GSMSettings and
+// ADPCMSettings.
+type EncoderSettings interface {
+	// encoderArgs returns the output format options for the settings
+	encoderArgs(output *AudioFormat) []string
+
+	// validateFor checks the settings against the output format
+	validateFor(output *AudioFormat) error
+}
+
+// mp3Bitrates lists the constant bitrates accepted by LAME, in kbps
+var mp3Bitrates = []int{8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 192, 224, 256, 320}
+
+// FLACSettings selects the FLAC compression level.
+//
+// Example:
+//
+//	task := sox.New(sox.PCM_RAW_8K_MONO, sox.FLAC_16K_MONO_LE).
+//		WithEncoder(sox.FLACSettings{Level: 8})
+type FLACSettings struct {
+	Level int // 0 (fastest) to 8 (smallest), sox defaults to 5
+}
+
+func (s FLACSettings) encoderArgs(_ *AudioFormat) []string {
+	return []string{"-C", strconv.Itoa(s.Level)}
+}
+
+func (s FLACSettings) validateFor(output *AudioFormat) error {
+	if err := requireEncoderType(s, output, TYPE_FLAC); err != nil {
+		return err
+	}
+
+	if s.Level < 0 || s.Level > 8 {
+		return encoderError(s, "Level must be between 0 and 8")
+	}
+
+	return nil
+}
+
+// MP3Settings selects constant (Bitrate) or variable (VBR) bitrate MP3 encoding.
+// Exactly one of Bitrate and VBR must be set.
+//
+// Example:
+//
+//	cbr := sox.MP3Settings{Bitrate: 128, Quality: 2} // -C 128.2
+//	vbr := sox.MP3Settings{VBR: true, VBRQuality: 4} // -C -4
+type MP3Settings struct {
+	Bitrate    int  // Constant bitrate in kbps, e.g. 128
+	VBR        bool // Use variable bitrate, with VBRQuality
+	VBRQuality int  // VBR quality 0 (best) to 9 (smallest)
+	Quality    int  // LAME algorithm quality 1 (best, slowest) to 9 (fastest); 0 keeps the encoder default
+}
+
+func (s MP3Settings) encoderArgs(_ *AudioFormat) []string {
+	value := strconv.Itoa(s.Bitrate)
+	if s.VBR {
+		value = "-" + strconv.Itoa(s.VBRQuality)
+	}
+
+	// The fractional part of -C selects the algorithm quality
+	if s.Quality > 0 {
+		value += "." + strconv.Itoa(s.Quality)
+	}
+
+	return []string{"-C", value}
+}
+
+func (s MP3Settings) validateFor(output *AudioFormat) error {
+	if err := requireEncoderType(s, output, TYPE_MP3); err != nil {
+		return err
+	}
+
+	if s.Quality < 0 || s.Quality > 9 {
+		return encoderError(s, "Quality must be between 0 and 9")
+	}
+
+	if s.VBR {
+		switch {
+		case s.Bitrate != 0:
+			return encoderError(s, "set either Bitrate or VBR, not both")
+		case s.VBRQuality < 0 || s.VBRQuality > 9:
+			return encoderError(s, "VBRQuality must be between 0 and 9")
+		case s.VBRQuality == 0 && s.Quality == 0:
+			// -C -0 is read as 0, which is not a VBR setting
+			return encoderError(s, "VBRQuality 0 requires Quality to be set")
+		}
+
+		return nil
+	}
+
+	for _, bitrate := range mp3Bitrates {
+		if s.Bitrate == bitrate {
+			return nil
+		}
+	}
+
+	if s.Bitrate == 0 {
+		return encoderError(s, "Bitrate or VBR is required")
+	}
+
+	return encoderError(s, fmt.Sprintf("Bitrate %d kbps is not a valid MP3 bitrate", s.Bitrate))
+}
+
+// VorbisSettings selects the Ogg Vorbis quality.
+//
+// Example:
+//
+//	task := sox.New(input, sox.AudioFormat{Type: sox.TYPE_OGG}).
+//		WithEncoder(sox.VorbisSettings{Quality: 6})
+type VorbisSettings struct {
+	Quality float64 // -1 (smallest) to 10 (best), sox defaults to 3
+}
+
+func (s VorbisSettings) encoderArgs(_ *AudioFormat) []string {
+	return []string{"-C", strconv.FormatFloat(s.Quality, 'f', -1, 64)}
+}
+
+func (s VorbisSettings) validateFor(output *AudioFormat) error {
+	if err := requireEncoderType(s, output, TYPE_OGG, "vorbis"); err != nil {
+		return err
+	}
+
+	if s.Quality < -1 || s.Quality > 10 {
+		return encoderError(s, "Quality must be between -1 and 10")
+	}
+
+	return nil
+}
+
+// GSMSettings encodes GSM 06.10 full rate, which is 8000 Hz mono only, into a
+// gsm file or a wav or raw output.
+//
+// Example:
+//
+//	task := sox.New(input, sox.AudioFormat{Type: sox.TYPE_WAV, SampleRate: 8000, Channels: 1}).
+//		WithEncoder(sox.GSMSettings{})
+type GSMSettings struct{}
+
+func (s GSMSettings) encoderArgs(output *AudioFormat) []string {
+	if strings.ToLower(output.Type) == TYPE_GSM || output.Encoding != "" {
+		return nil
+	}
+
+	return []string{"-e", string(GSM_FULL_RATE)}
+}
+
+func (s GSMSettings) validateFor(output *AudioFormat) error {
+	if err := requireEncoderType(s, output, TYPE_GSM, TYPE_WAV, TYPE_RAW); err != nil {
+		return err
+	}
+
+	switch {
+	case output.Encoding != "" && output.Encoding.Canonical() != GSM_FULL_RATE:
+		return encoderError(s, fmt.Sprintf("output Encoding is %s", output.Encoding))
+	case output.SampleRate != 0 && output.SampleRate != 8000:
+		return encoderError(s, "GSM requires an 8000 Hz output")
+	case output.Channels > 1:
+		return encoderError(s, "GSM requires a mono output")
+	}
+
+	return nil
+}
+
+// adpcmTypes lists the output types that can hold each ADPCM variant
+var adpcmTypes = map[Encoding][]string{
+	IMA_ADPCM: {TYPE_WAV, TYPE_RAW, "ima"},
+	MS_ADPCM:  {TYPE_WAV},
+	OKI_ADPCM: {TYPE_RAW, "vox"},
+}
+
+// ADPCMSettings encodes 4-bit ADPCM: IMA ADPCM in wav or raw, Microsoft ADPCM
+// in wav, or OKI (Dialogic VOX) ADPCM in raw or vox outputs.
+//
+// Example:
+//
+//	task := sox.New(input, sox.AudioFormat{Type: sox.TYPE_WAV, SampleRate: 16000, Channels: 1}).
+//		WithEncoder(sox.ADPCMSettings{Encoding: sox.IMA_ADPCM})
+type ADPCMSettings struct {
+	Encoding Encoding // IMA_ADPCM, MS_ADPCM or OKI_ADPCM
+}
+
+func (s ADPCMSettings) encoderArgs(output *AudioFormat) []string {
+	var args []string
+
+	if output.Encoding == "" {
+		args = append(args, "-e", string(s.Encoding.Canonical()))
+	}
+
+	if output.BitDepth == 0 {
+		args = append(args, "-b", "4")
+	}
+
+	return args
+}
+
+func (s ADPCMSettings) validateFor(output *AudioFormat) error {
+	encoding := s.Encoding.Canonical()
+
+	types, ok := adpcmTypes[encoding]
+	if !ok {
+		return encoderError(s, "Encoding must be IMA_ADPCM, MS_ADPCM or OKI_ADPCM")
+	}
+
+	if err := requireEncoderType(s, output, types...); err != nil {
+		return err
+	}
+
+	switch {
+	case output.Encoding != "" && output.Encoding.Canonical() != encoding:
+		return encoderError(s, fmt.Sprintf("output Encoding is %s", output.Encoding))
+	case output.BitDepth != 0 && output.BitDepth != 4:
+		return encoderError(s, "ADPCM requires a 4-bit output")
+	}
+
+	return nil
+}
+
+// requireEncoderType returns an error unless the output type is one of types
+func requireEncoderType(settings EncoderSettings, output *AudioFormat, types ...string) error {
+	outputType := strings.ToLower(output.Type)
+
+	for _, typ := range types {
+		if outputType == typ {
+			return nil
+		}
+	}
+
+	return encoderError(settings, fmt.Sprintf("output type is %q, expected %s", output.Type, strings.Join(types, " or ")))
+}
+
+// encoderError reports invalid encoder settings
+func encoderError(settings EncoderSettings, reason string) error {
+	return &FormatError{Field: "Encoder", Value: settings, Reason: reason}
+}
@@ -0,0 +1,125 @@
+package sox
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Encoding is a sox sample encoding, passed with -e|--encoding
+type Encoding string
+
+const (
+	SIGNED_INTEGER   Encoding = "signed-integer"
+	UNSIGNED_INTEGER Encoding = "unsigned-integer"
+	FLOATING_POINT   Encoding = "floating-point"
+	MU_LAW           Encoding = "mu-law"
+	A_LAW            Encoding = "a-law"
+	IMA_ADPCM        Encoding = "ima-adpcm"
+	MS_ADPCM         Encoding = "ms-adpcm"
+	OKI_ADPCM        Encoding = "oki-adpcm"
+	GSM_FULL_RATE    Encoding = "gsm-full-rate"
+
+	// Deprecated: sox has no GSM half-rate codec, formats using it fail validation.
+	GSM_HALF_RATE Encoding = "gsm-half-rate"
+)
+
+// encodingAliases maps alternative spellings to sox encodings
+var encodingAliases = map[string]Encoding{
+	"signed-integer":   SIGNED_INTEGER,
+	"signed":           SIGNED_INTEGER,
+	"unsigned-integer": UNSIGNED_INTEGER,
+	"unsigned":         UNSIGNED_INTEGER,
+	"floating-point":   FLOATING_POINT,
+	"float":            FLOATING_POINT,
+	"mu-law":           MU_LAW,
+	"u-law":            MU_LAW,
+	"ulaw":             MU_LAW,
+	"mulaw":            MU_LAW,
+	"a-law":            A_LAW,
+	"alaw":             A_LAW,
+	"ima-adpcm":        IMA_ADPCM,
+	"ima":              IMA_ADPCM,
+	"ms-adpcm":         MS_ADPCM,
+	"ms":               MS_ADPCM,
+	"oki-adpcm":        OKI_ADPCM,
+	"oki":              OKI_ADPCM,
+	"gsm-full-rate":    GSM_FULL_RATE,
+	"gsm":              GSM_FULL_RATE,
+}
+
+// encodingBitDepths lists the bit depths sox supports for each encoding
+var encodingBitDepths = map[Encoding][]int{
+	SIGNED_INTEGER:   {8, 16, 24, 32},
+	UNSIGNED_INTEGER: {8, 16, 24, 32},
+	FLOATING_POINT:   {32, 64},
+	MU_LAW:           {8},
+	A_LAW:            {8},
+	IMA_ADPCM:        {4},
+	MS_ADPCM:         {4},
+	OKI_ADPCM:        {4},
+}
+
+// typeEncodings lists the encodings each file type can hold. Types not listed
+// (raw and the many headerless sox types) accept any encoding; an empty list
+// means the codec decides and no encoding may be given.
+var typeEncodings = map[string][]Encoding{
+	TYPE_WAV:  {SIGNED_INTEGER, UNSIGNED_INTEGER, FLOATING_POINT, MU_LAW, A_LAW, IMA_ADPCM, MS_ADPCM, GSM_FULL_RATE},
+	TYPE_FLAC: {SIGNED_INTEGER},
+	TYPE_AIFF: {SIGNED_INTEGER},
+	TYPE_AU:   {SIGNED_INTEGER, FLOATING_POINT, MU_LAW, A_LAW},
+	TYPE_ALAW: {A_LAW},
+	TYPE_GSM:  {GSM_FULL_RATE},
+	TYPE_MP3:  {},
+	TYPE_OGG:  {},
+	TYPE_OPUS: {},
+	TYPE_M4A:  {},
+	TYPE_AAC:  {},
+	TYPE_AC3:  {},
+	TYPE_EAC3: {},
+}
+
+// ParseEncoding returns the sox encoding for a name or alias such as "signed",
+// "float", "ulaw" or "ima". Names are case-insensitive.
+//
+// Example:
+//
+//	enc, err := sox.ParseEncoding("ulaw") // sox.MU_LAW
+func ParseEncoding(name string) (Encoding, error) {
+	if enc, ok := encodingAliases[strings.ToLower(strings.TrimSpace(name))]; ok {
+		return enc, nil
+	}
+
+	return "", fmt.Errorf("%w: unknown encoding %q", ErrInvalidFormat, name)
+}
+
+// Canonical returns the sox name of the encoding, resolving aliases.
+// Unknown encodings are returned unchanged.
+func (e Encoding) Canonical() Encoding {
+	if enc, ok := encodingAliases[strings.ToLower(string(e))]; ok {
+		return enc
+	}
+
+	return e
+}
+
+// IsValid reports whether the encoding, or the encoding it is an alias of, is known to sox
+func (e Encoding) IsValid() bool {
+	_, ok := encodingAliases[strings.ToLower(string(e))]
+	return ok
+}
+
+// supportsBitDepth reports whether sox can store samples of the given size in this encoding
+func (e Encoding) supportsBitDepth(bits int) bool {
+	depths, ok := encodingBitDepths[e.Canonical()]
+	if !ok {
+		return true // GSM and unknown encodings have no fixed sample size
+	}
+
+	for _, depth := range depths {
+		if depth == bits {
+			return true
+		}
+	}
+
+	return false
+}
@@ -166,11 +166,11 @@ func exampleCustomOptions() {
 
 	// Configure options
 	opts := sox.DefaultOptions()
-	opts.CompressionLevel = 8       // Maximum FLAC compression
 	opts.BufferSize = 64 * 1024     // 64KB buffer
 	opts.Effects = []string{"norm"} // Normalize audio
 
 	converter.WithOptions(opts)
+	converter.WithEncoder(sox.FLACSettings{Level: 8}) // Maximum FLAC compression
 
 	// Convert
 	input := bytes.NewReader(pcmData)
@@ -203,7 +203,7 @@ func exampleCustomFormat() {
 
 	wavTelephony := sox.AudioFormat{
 		Type:       "wav",
-		Encoding:   "little-endian",
+		Encoding:   "unsigned-integer",
 		SampleRate: 8000,
 		Channels:   1,
 		BitDepth:   8,
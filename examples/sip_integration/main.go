@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"sync"
@@ -22,20 +23,18 @@ func (t *TranscriptionAPI) Transcribe(audioData []byte) (string, error) {
 
 // RTPMediaHandler handles incoming RTP media and converts to FLAC for transcription
 type RTPMediaHandler struct {
-	stream           *sox.Task
-	transcriptionCh  chan []byte
-	maxBufferMs      int
-	accumulatedMs    int
-	packetDurationMs int
-	mu               sync.Mutex
+	stream          *sox.Task
+	transcriptionCh chan []byte
+	maxBuffer       time.Duration
+	accumulated     time.Duration
+	mu              sync.Mutex
 }
 
 // NewRTPMediaHandler creates a handler for RTP → FLAC → Transcription pipeline
-func NewRTPMediaHandler(maxBufferMs, packetDurationMs int) *RTPMediaHandler {
+func NewRTPMediaHandler(maxBuffer time.Duration) *RTPMediaHandler {
 	return &RTPMediaHandler{
-		maxBufferMs:      maxBufferMs,
-		packetDurationMs: packetDurationMs,
-		transcriptionCh:  make(chan []byte, 10),
+		maxBuffer:       maxBuffer,
+		transcriptionCh: make(chan []byte, 10),
 	}
 }
 
@@ -46,10 +45,13 @@ func (h *RTPMediaHandler) Start() error {
 
 	// Configure for optimal performance
 	opts := sox.DefaultOptions()
-	opts.CompressionLevel = 5 // Balance between size and speed
 	opts.BufferSize = 64 * 1024
 	h.stream.WithOptions(opts)
+	h.stream.WithEncoder(sox.FLACSettings{Level: 5}) // Balance between size and speed
+	// Without a chunk handler or output path, ticks leave the audio buffered,
+	// so each Flush below returns everything written since the previous one
 	h.stream.WithTicker(3 * time.Second)
+	h.stream.WithFrameAlignment() // Never split a sample across flushes
 
 	h.stream.Start()
 	return nil
@@ -61,26 +63,41 @@ func (h *RTPMediaHandler) HandleRTPPacket(pcmData []byte) error {
 	defer h.mu.Unlock()
 
 	// Write PCM data to SoX stream
-	_, err := h.stream.Write(pcmData)
+	n, err := h.stream.Write(pcmData)
 	if err != nil {
 		return fmt.Errorf("failed to write RTP packet: %w", err)
 	}
 
-	h.accumulatedMs += h.packetDurationMs
+	// Packet duration follows from the payload size, whatever the packetization
+	h.accumulated += h.stream.Input.Duration(n)
 
 	// Check if we've accumulated enough audio for transcription
-	if h.accumulatedMs >= h.maxBufferMs {
-		// Flush and get FLAC output
-		err := h.stream.Stop()
-		if err != nil {
-			return fmt.Errorf("failed to flush stream: %w", err)
-		}
+	if h.accumulated >= h.maxBuffer {
+		return h.flushToTranscription()
+	}
 
-		// Send to transcription worker
-		select {
-		default:
-			log.Println("Warning: transcription queue full, dropping packet")
-		}
+	return nil
+}
+
+// flushToTranscription converts the buffered audio to FLAC without ending the call
+// and hands it to the transcription worker (assumes lock is held)
+func (h *RTPMediaHandler) flushToTranscription() error {
+	flacData, err := h.stream.Flush(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to flush stream: %w", err)
+	}
+
+	h.accumulated = 0
+
+	if len(flacData) == 0 {
+		return nil
+	}
+
+	// Send to transcription worker
+	select {
+	case h.transcriptionCh <- flacData:
+	default:
+		log.Println("Warning: transcription queue full, dropping chunk")
 	}
 
 	return nil
@@ -105,10 +122,9 @@ func (h *RTPMediaHandler) Stop() error {
 	defer h.mu.Unlock()
 
 	// Flush any remaining data
-	if h.accumulatedMs > 0 {
-		err := h.stream.Stop()
-		if err != nil {
-			return fmt.Errorf("failed to flush stream: %w", err)
+	if h.accumulated > 0 {
+		if err := h.flushToTranscription(); err != nil {
+			return err
 		}
 	}
 
@@ -144,7 +160,7 @@ func main() {
 
 	// Create RTP handler
 	// Accumulate 3 seconds of audio before sending to transcription
-	handler := NewRTPMediaHandler(3000, 20) // 3s max buffer, 20ms packets
+	handler := NewRTPMediaHandler(3 * time.Second) // 3s max buffer
 
 	// Start handler
 	if err := handler.Start(); err != nil {
@@ -1,9 +1,11 @@
 package sox
 
 import (
+	"errors"
 	"fmt"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 const (
@@ -12,11 +14,15 @@ const (
 	TYPE_WAV            = "wav"
 	TYPE_MP3            = "mp3"
 	TYPE_OGG            = "ogg"
+	TYPE_OPUS           = "opus"
 	TYPE_M4A            = "m4a"
 	TYPE_AAC            = "aac"
 	TYPE_AC3            = "ac3"
 	TYPE_EAC3           = "eac3"
 	TYPE_ALAW           = "alaw"
+	TYPE_AIFF           = "aiff"
+	TYPE_AU             = "au"
+	TYPE_GSM            = "gsm"
 	TYPE_IMA_ADPCM      = "ima-adpcm"
 	TYPE_MS_ADPCM       = "ms-adpcm"
 	TYPE_GSM_FULL_RATE  = "gsm-full-rate"
@@ -26,24 +32,21 @@ const (
 	TYPE_GSM_HR_RATE    = "gsm-hr-rate"
 	TYPE_GSM_MR_RATE    = "gsm-mr-rate"
 	TYPE_GSM_SUPER_RATE = "gsm-super-rate"
-	SIGNED_INTEGER      = "signed-integer"
-	UNSIGNED_INTEGER    = "unsigned-integer"
-	FLOATING_POINT      = "floating-point"
-	MU_LAW              = "mu-law"
-	A_LAW               = "a-law"
-	IMA_ADPCM           = "ima-adpcm"
-	MS_ADPCM            = "ms-adpcm"
-	GSM_FULL_RATE       = "gsm-full-rate"
-	GSM_HALF_RATE       = "gsm-half-rate"
+)
+
+const (
+	minSampleRate = 1000   // Lowest sample rate accepted by Validate, in Hz
+	maxSampleRate = 768000 // Highest sample rate accepted by Validate, in Hz
+	maxChannels   = 64     // Highest channel count accepted by Validate
 )
 
 // AudioFormat defines the audio format parameters for input or output
 type AudioFormat struct {
-	Type       string // "raw", "flac", "wav", "mp3", "ogg", etc.
-	Encoding   string // "signed-integer", "unsigned-integer", "floating-point", "mu-law", "a-law", "ima-adpcm", "ms-adpcm", "gsm-full-rate"
-	SampleRate int    // Sample rate in Hz (e.g., 8000, 16000, 44100, 48000)
-	Channels   int    // Number of channels: 1 = mono, 2 = stereo
-	BitDepth   int    // Bits per sample: 8, 16, 24, 32
+	Type       string   // "raw", "flac", "wav", "mp3", "ogg", etc.
+	Encoding   Encoding // SIGNED_INTEGER, UNSIGNED_INTEGER, FLOATING_POINT, MU_LAW, A_LAW, IMA_ADPCM, MS_ADPCM, GSM_FULL_RATE or an alias
+	SampleRate int      // Sample rate in Hz (e.g., 8000, 16000, 44100, 48000)
+	Channels   int      // Number of channels: 1 = mono, 2 = stereo
+	BitDepth   int      // Bits per sample: 8, 16, 24, 32
 
 	// Extended format options - supports all SoX format parameters
 	Volume         float64 // -v|--volume FACTOR - Input file volume adjustment factor
@@ -81,7 +84,7 @@ var (
 
 	FLAC_16K_MONO_LE = AudioFormat{
 		Type:       "flac",
-		Encoding:   "unsigned",
+		Encoding:   "signed-integer",
 		Endian:     "little",
 		SampleRate: 16000,
 		Channels:   1,
@@ -90,7 +93,7 @@ var (
 
 	WAV_8K_MONO_LE = AudioFormat{
 		Type:       "wav",
-		Encoding:   "signed",
+		Encoding:   "unsigned-integer",
 		Endian:     "little",
 		SampleRate: 8000,
 		Channels:   1,
@@ -122,21 +125,165 @@ var (
 		Channels:   1,
 		BitDepth:   8,
 	}
+
+	// ALAW_8K_MONO - G.711 A-law 8kHz mono (telephony standard outside North America and Japan)
+	ALAW_8K_MONO = AudioFormat{
+		Type:       "raw",
+		Encoding:   "a-law",
+		SampleRate: 8000,
+		Channels:   1,
+		BitDepth:   8,
+	}
+
+	// PCM_RAW_16K_MONO - PCM Raw 16kHz mono 16-bit (wideband voice, speech recognition)
+	PCM_RAW_16K_MONO = AudioFormat{
+		Type:       TYPE_RAW,
+		Encoding:   "signed-integer",
+		SampleRate: 16000,
+		Channels:   1,
+		BitDepth:   16,
+	}
+
+	// L16_16K_MONO - RTP L16 16kHz mono: 16-bit big-endian PCM (RFC 3551)
+	L16_16K_MONO = AudioFormat{
+		Type:       TYPE_RAW,
+		Encoding:   "signed-integer",
+		Endian:     "big",
+		SampleRate: 16000,
+		Channels:   1,
+		BitDepth:   16,
+	}
+
+	// L16_48K_STEREO - RTP L16 48kHz stereo: 16-bit big-endian PCM (RFC 3551)
+	L16_48K_STEREO = AudioFormat{
+		Type:       TYPE_RAW,
+		Encoding:   "signed-integer",
+		Endian:     "big",
+		SampleRate: 48000,
+		Channels:   2,
+		BitDepth:   16,
+	}
+
+	// GSM_8K_MONO - GSM 06.10 full rate 8kHz mono
+	GSM_8K_MONO = AudioFormat{
+		Type:       TYPE_GSM,
+		SampleRate: 8000,
+		Channels:   1,
+	}
+
+	// FLAC_16K_MONO - FLAC 16kHz mono 16-bit (voice archiving)
+	FLAC_16K_MONO = AudioFormat{
+		Type:       TYPE_FLAC,
+		Encoding:   "signed-integer",
+		SampleRate: 16000,
+		Channels:   1,
+		BitDepth:   16,
+	}
+
+	// FLAC_48K_STEREO - FLAC 48kHz stereo 24-bit (media mastering)
+	FLAC_48K_STEREO = AudioFormat{
+		Type:       TYPE_FLAC,
+		Encoding:   "signed-integer",
+		SampleRate: 48000,
+		Channels:   2,
+		BitDepth:   24,
+	}
+
+	// WAV_44K_STEREO - WAV 44.1kHz stereo 16-bit (CD quality)
+	WAV_44K_STEREO = AudioFormat{
+		Type:       TYPE_WAV,
+		Encoding:   "signed-integer",
+		SampleRate: 44100,
+		Channels:   2,
+		BitDepth:   16,
+	}
+
+	// WAV_48K_STEREO - WAV 48kHz stereo 16-bit (video and broadcast)
+	WAV_48K_STEREO = AudioFormat{
+		Type:       TYPE_WAV,
+		Encoding:   "signed-integer",
+		SampleRate: 48000,
+		Channels:   2,
+		BitDepth:   16,
+	}
+
+	// MP3_VOICE - MP3 16kHz mono at 32 kbps CBR (voice messages, call recordings)
+	MP3_VOICE = AudioFormat{
+		Type:        TYPE_MP3,
+		SampleRate:  16000,
+		Channels:    1,
+		Compression: 32,
+	}
+
+	// MP3_MUSIC - MP3 44.1kHz stereo at 192 kbps CBR
+	MP3_MUSIC = AudioFormat{
+		Type:        TYPE_MP3,
+		SampleRate:  44100,
+		Channels:    2,
+		Compression: 192,
+	}
+
+	// OGG_VOICE - Ogg Vorbis 16kHz mono at quality 3
+	OGG_VOICE = AudioFormat{
+		Type:        TYPE_OGG,
+		SampleRate:  16000,
+		Channels:    1,
+		Compression: 3,
+	}
+)
+
+// FormatRole tells whether an AudioFormat describes the input or the output of sox.
+// Some format options are only valid on one side.
+type FormatRole int
+
+const (
+	RoleInput  FormatRole = iota // Format of the file or stream sox reads
+	RoleOutput                   // Format of the file or stream sox writes
 )
 
+// String returns "input" or "output"
+func (r FormatRole) String() string {
+	if r == RoleOutput {
+		return "output"
+	}
+
+	return "input"
+}
+
 // BuildArgs converts AudioFormat to SoX command-line arguments
 // Supports all SoX format options without discriminating file types
-// isInput: true for input format, false for output format
+//
+// Deprecated: BuildArgs emits input-only and output-only options alike; use
+// BuildArgsFor, which only emits the options valid for the given role.
 func (f *AudioFormat) BuildArgs() []string {
+	return f.buildArgs(true, true)
+}
+
+// BuildArgsFor converts AudioFormat to the SoX format options for one side of the
+// command, to be placed right before the input or output file name. Options not
+// valid for the role (see ValidateFor) are left out.
+//
+// Example:
+//
+//	args := append(input.BuildArgsFor(sox.RoleInput), "in.raw")
+//	args = append(args, output.BuildArgsFor(sox.RoleOutput)...)
+//	args = append(args, "out.flac")
+func (f *AudioFormat) BuildArgsFor(role FormatRole) []string {
+	return f.buildArgs(role == RoleInput, role == RoleOutput)
+}
+
+// buildArgs emits the options shared by both roles, plus the input-only and
+// output-only ones when requested
+func (f *AudioFormat) buildArgs(input, output bool) []string {
 	var args []string
 
 	// Volume adjustment (input only)
-	if f.Volume != 0 {
+	if input && f.Volume != 0 {
 		args = append(args, "-v", fmt.Sprintf("%f", f.Volume))
 	}
 
 	// Ignore length (input only)
-	if f.IgnoreLength {
+	if input && f.IgnoreLength {
 		args = append(args, "--ignore-length")
 	}
 
@@ -147,7 +294,7 @@ func (f *AudioFormat) BuildArgs() []string {
 
 	// Encoding
 	if f.Encoding != "" {
-		args = append(args, "-e", f.Encoding)
+		args = append(args, "-e", string(f.Encoding.Canonical()))
 	}
 
 	// Bit depth
@@ -181,22 +328,22 @@ func (f *AudioFormat) BuildArgs() []string {
 	}
 
 	// Compression (output only)
-	if f.Compression != 0 {
+	if output && f.Compression != 0 {
 		args = append(args, "-C", fmt.Sprintf("%f", f.Compression))
 	}
 
 	// Comment (output only)
-	if f.Comment != "" {
+	if output && f.Comment != "" {
 		args = append(args, "--comment", f.Comment)
 	}
 
 	// Add comment (output only)
-	if f.AddComment != "" {
+	if output && f.AddComment != "" {
 		args = append(args, "--add-comment", f.AddComment)
 	}
 
 	// Comment file (output only)
-	if f.CommentFile != "" {
+	if output && f.CommentFile != "" {
 		args = append(args, "--comment-file", f.CommentFile)
 	}
 
@@ -218,19 +365,246 @@ func (f *AudioFormat) BuildArgs() []string {
 	return args
 }
 
-// Validate checks if the AudioFormat has valid parameters
-// More flexible validation that allows users to configure their own parameters
+// FormatError reports an invalid AudioFormat field. It matches ErrInvalidFormat
+// with errors.Is.
+type FormatError struct {
+	Field  string      // AudioFormat field name, e.g. "BitDepth"
+	Value  interface{} // Offending value
+	Reason string
+}
+
+func (e *FormatError) Error() string {
+	return fmt.Sprintf("invalid %s %#v: %s", e.Field, e.Value, e.Reason)
+}
+
+// Unwrap allows errors.Is(err, ErrInvalidFormat)
+func (e *FormatError) Unwrap() error {
+	return ErrInvalidFormat
+}
+
+// Validate checks if the AudioFormat has valid parameters, including combinations
+// sox would reject or silently change: bit depth vs encoding, the encodings each
+// type can hold, and channel and sample rate bounds. Unset fields are not checked.
+// The returned *FormatError names the offending field.
+//
+// Example:
+//
+//	format := sox.AudioFormat{Type: "flac", Encoding: sox.UNSIGNED_INTEGER, BitDepth: 16}
+//	err := format.Validate()
+//	// invalid Encoding "unsigned-integer": flac files hold signed-integer
 func (f *AudioFormat) Validate() error {
 	// Validate endian values if specified
 	if f.Endian != "" {
 		if f.Endian != "little" && f.Endian != "big" && f.Endian != "swap" {
-			return fmt.Errorf("endian must be 'little', 'big', or 'swap'")
+			return &FormatError{Field: "Endian", Value: f.Endian, Reason: "must be little, big or swap"}
 		}
 	}
 
+	if f.SampleRate != 0 && (f.SampleRate < minSampleRate || f.SampleRate > maxSampleRate) {
+		return &FormatError{Field: "SampleRate", Value: f.SampleRate,
+			Reason: fmt.Sprintf("must be between %d and %d Hz", minSampleRate, maxSampleRate)}
+	}
+
+	if f.Channels < 0 || f.Channels > maxChannels {
+		return &FormatError{Field: "Channels", Value: f.Channels,
+			Reason: fmt.Sprintf("must be between 1 and %d", maxChannels)}
+	}
+
+	if f.BitDepth < 0 {
+		return &FormatError{Field: "BitDepth", Value: f.BitDepth, Reason: "must be positive"}
+	}
+
+	if f.Encoding != "" {
+		if err := f.validateEncoding(); err != nil {
+			return err
+		}
+	}
+
+	switch strings.ToLower(f.Type) {
+	case TYPE_FLAC:
+		if f.BitDepth != 0 && f.BitDepth != 8 && f.BitDepth != 16 && f.BitDepth != 24 {
+			return &FormatError{Field: "BitDepth", Value: f.BitDepth, Reason: "flac supports 8, 16 or 24 bits"}
+		}
+	case TYPE_WAV:
+		// WAV PCM is unsigned at 8 bits and signed above
+		encoding := f.Encoding.Canonical()
+		if encoding == SIGNED_INTEGER && f.BitDepth == 8 {
+			return &FormatError{Field: "Encoding", Value: f.Encoding, Reason: "8-bit wav is unsigned-integer"}
+		}
+		if encoding == UNSIGNED_INTEGER && f.BitDepth > 8 {
+			return &FormatError{Field: "Encoding", Value: f.Encoding, Reason: "wav wider than 8 bits is signed-integer"}
+		}
+	}
+
+	return nil
+}
+
+// ValidateFor runs Validate and also rejects options that sox does not accept for
+// the role: Volume and IgnoreLength are input-only, Compression, Comment,
+// AddComment and CommentFile are output-only.
+//
+// Example:
+//
+//	output := sox.AudioFormat{Type: "flac", Volume: 2}
+//	err := output.ValidateFor(sox.RoleOutput)
+//	// invalid Volume 2: input-only option, not valid on the output
+func (f *AudioFormat) ValidateFor(role FormatRole) error {
+	if err := f.Validate(); err != nil {
+		return err
+	}
+
+	if role == RoleOutput {
+		switch {
+		case f.Volume != 0:
+			return roleError("Volume", f.Volume, RoleInput)
+		case f.IgnoreLength:
+			return roleError("IgnoreLength", f.IgnoreLength, RoleInput)
+		}
+
+		return nil
+	}
+
+	switch {
+	case f.Compression != 0:
+		return roleError("Compression", f.Compression, RoleOutput)
+	case f.Comment != "":
+		return roleError("Comment", f.Comment, RoleOutput)
+	case f.AddComment != "":
+		return roleError("AddComment", f.AddComment, RoleOutput)
+	case f.CommentFile != "":
+		return roleError("CommentFile", f.CommentFile, RoleOutput)
+	}
+
 	return nil
 }
 
+// roleError reports an option set on the side where sox does not accept it
+func roleError(field string, value interface{}, only FormatRole) error {
+	other := RoleOutput
+	if only == RoleOutput {
+		other = RoleInput
+	}
+
+	return &FormatError{Field: field, Value: value, Reason: fmt.Sprintf("%s-only option, not valid on the %s", only, other)}
+}
+
+// validateEncoding checks Encoding on its own, against BitDepth and against Type
+func (f *AudioFormat) validateEncoding() error {
+	if !f.Encoding.IsValid() {
+		return &FormatError{Field: "Encoding", Value: f.Encoding, Reason: "unknown encoding"}
+	}
+
+	encoding := f.Encoding.Canonical()
+
+	if f.BitDepth > 0 && !encoding.supportsBitDepth(f.BitDepth) {
+		return &FormatError{Field: "BitDepth", Value: f.BitDepth,
+			Reason: fmt.Sprintf("%s supports %s bits", encoding, joinInts(encodingBitDepths[encoding]))}
+	}
+
+	allowed, ok := typeEncodings[strings.ToLower(f.Type)]
+	if !ok {
+		return nil
+	}
+
+	for _, enc := range allowed {
+		if enc == encoding {
+			return nil
+		}
+	}
+
+	if len(allowed) == 0 {
+		return &FormatError{Field: "Encoding", Value: f.Encoding,
+			Reason: fmt.Sprintf("%s files are encoded by their codec, leave it empty", f.Type)}
+	}
+
+	names := make([]string, len(allowed))
+	for i, enc := range allowed {
+		names[i] = string(enc)
+	}
+
+	return &FormatError{Field: "Encoding", Value: f.Encoding,
+		Reason: fmt.Sprintf("%s files hold %s", f.Type, strings.Join(names, ", "))}
+}
+
+// joinInts formats values as "8, 16 or 24"
+func joinInts(values []int) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = fmt.Sprintf("%d", v)
+	}
+
+	if len(parts) < 2 {
+		return strings.Join(parts, "")
+	}
+
+	return strings.Join(parts[:len(parts)-1], ", ") + " or " + parts[len(parts)-1]
+}
+
+// FrameSize returns the number of bytes in one sample frame (one sample per channel),
+// or 0 when BitDepth or Channels is not set. It only applies to uncompressed audio.
+//
+// Example:
+//
+//	sox.PCM_RAW_16K_MONO.FrameSize() // 2
+//	sox.WAV_48K_STEREO.FrameSize()   // 4
+func (f *AudioFormat) FrameSize() int {
+	if f.BitDepth <= 0 || f.Channels <= 0 {
+		return 0
+	}
+
+	return (f.BitDepth + 7) / 8 * f.Channels
+}
+
+// BytesPerSecond returns the byte rate of uncompressed audio in this format,
+// or 0 when SampleRate, BitDepth or Channels is not set.
+//
+// Example:
+//
+//	sox.PCM_RAW_16K_MONO.BytesPerSecond() // 32000
+func (f *AudioFormat) BytesPerSecond() int {
+	return f.FrameSize() * f.SampleRate
+}
+
+// Duration returns the playing time of n bytes of uncompressed audio in this
+// format, or 0 when the byte rate is unknown.
+//
+// Example:
+//
+//	n, _ := task.Write(payload)
+//	elapsed += task.Input.Duration(n) // 20ms for 640 bytes of PCM_RAW_16K_MONO
+func (f *AudioFormat) Duration(n int) time.Duration {
+	bps := int64(f.BytesPerSecond())
+	if bps == 0 {
+		return 0
+	}
+
+	// Split into whole seconds first so large sizes cannot overflow
+	seconds := int64(n) / bps
+	rest := int64(n) % bps
+
+	return time.Duration(seconds)*time.Second + time.Duration(rest*int64(time.Second)/bps)
+}
+
+// BytesFor returns the number of bytes holding d of uncompressed audio in this
+// format, rounded down to whole frames, or 0 when the byte rate is unknown.
+//
+// Example:
+//
+//	packet := make([]byte, sox.ULAW_8K_MONO.BytesFor(20*time.Millisecond)) // 160 bytes
+func (f *AudioFormat) BytesFor(d time.Duration) int {
+	frame := int64(f.FrameSize())
+	if frame == 0 || f.SampleRate <= 0 || d <= 0 {
+		return 0
+	}
+
+	// Count whole frames, splitting seconds off so long durations cannot overflow
+	seconds := int64(d / time.Second)
+	rest := int64(d % time.Second)
+	frames := seconds*int64(f.SampleRate) + rest*int64(f.SampleRate)/int64(time.Second)
+
+	return int(frames * frame)
+}
+
 // toAudioFormatPtr converts an interface{} to *AudioFormat, accepting both values and pointers
 func toAudioFormatPtr(v interface{}) *AudioFormat {
 	switch val := v.(type) {
@@ -243,37 +617,79 @@ func toAudioFormatPtr(v interface{}) *AudioFormat {
 	}
 }
 
-// detectInputFormat detects the input format based on the input type.
+// ErrExtensionMismatch is returned when an output path extension contradicts Output.Type
+var ErrExtensionMismatch = errors.New("output path extension does not match output type")
+
+// rawTypes are the types and extensions of headerless audio, which are
+// interchangeable as far as the extension check is concerned
+var rawTypes = map[string]bool{
+	TYPE_RAW: true, "pcm": true, "sln": true, TYPE_ALAW: true, "al": true, "ul": true, "ulaw": true,
+	"u8": true, "s8": true, "u16": true, "s16": true, "s24": true, "s32": true, "f32": true, "f64": true,
+}
+
+// typeFromExtension returns the audio type of a path from its extension,
+// or "" when the extension is unknown
+func typeFromExtension(path string) string {
+	ext := strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+
+	switch ext {
+	case "wav", "flac", "mp3":
+		return ext
+	case "ogg", "oga":
+		return TYPE_OGG
+	case "opus":
+		return TYPE_OPUS
+	case "m4a":
+		return TYPE_M4A
+	case "aac":
+		return TYPE_AAC
+	case "ac3":
+		return TYPE_AC3
+	case "eac3":
+		return TYPE_EAC3
+	case "aiff", "aif", "aifc":
+		return TYPE_AIFF
+	case "au", "snd":
+		return TYPE_AU
+	case "gsm":
+		return TYPE_GSM
+	case "alaw", "al":
+		return TYPE_ALAW
+	case "pcm", "raw", "sln":
+		// Common raw audio file extensions
+		return TYPE_RAW
+	}
+
+	return ""
+}
+
+// extensionMatchesType reports whether a path extension is consistent with an explicit type
+func extensionMatchesType(path, typ string) bool {
+	ext := strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	extType := typeFromExtension(path)
+
+	if extType == "" && !rawTypes[ext] {
+		return true // unknown extension, nothing to contradict
+	}
+
+	typ = strings.ToLower(typ)
+	if typ == extType {
+		return true
+	}
+
+	return rawTypes[typ] && (rawTypes[ext] || rawTypes[extType])
+}
+
+// toFormatType detects the input format based on the input type.
 // For file paths: detects by extension (wav/flac/mp3 auto-detected, others default to raw).
-// For io.Reader: defaults to raw format.
+// For io.Reader: defaults to raw format, see SniffFormat for content detection.
 func toFormatType(input interface{}) *AudioFormat {
 	inputFormat := &AudioFormat{Type: TYPE_RAW}
 
 	// If input is a string (file path), try to detect by extension
 	if inputPath, ok := input.(string); ok {
-		ext := strings.TrimPrefix(strings.ToLower(filepath.Ext(inputPath)), ".")
-		// For other extensions, try to map them
-		switch ext {
-		case "wav", "flac", "mp3":
-			inputFormat.Type = ext
-		case "ogg":
-			inputFormat.Type = TYPE_OGG
-		case "m4a":
-			inputFormat.Type = TYPE_M4A
-		case "aac":
-			inputFormat.Type = TYPE_AAC
-		case "ac3":
-			inputFormat.Type = TYPE_AC3
-		case "eac3":
-			inputFormat.Type = TYPE_EAC3
-		case "alaw", "al":
-			inputFormat.Type = TYPE_ALAW
-		case "pcm", "raw", "sln":
-			// Common raw audio file extensions
-			inputFormat.Type = TYPE_RAW
-		default:
-			// Default to raw for unknown extensions
-			inputFormat.Type = TYPE_RAW
+		if typ := typeFromExtension(inputPath); typ != "" {
+			inputFormat.Type = typ
 		}
 	}
 
@@ -0,0 +1,466 @@
+package sox
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+)
+
+// Loudness holds an ITU-R BS.1770-4 / EBU R 128 loudness measurement
+type Loudness struct {
+	Integrated float64 // Integrated loudness in LUFS; -Inf for silence or audio shorter than 400ms
+	Range      float64 // Loudness range (LRA) in LU, per EBU Tech 3342
+	TruePeak   float64 // Highest true peak across channels in dBTP, from 4x oversampling
+}
+
+// LoudnessTarget is the goal of loudness normalization
+type LoudnessTarget struct {
+	Integrated float64 // Target integrated loudness in LUFS, e.g. -16 for voice prompts, -23 for EBU R 128
+	TruePeak   float64 // True peak ceiling in dBTP, e.g. -1; a limiter holds peaks below it
+	MaxGain    float64 // Largest gain in dB, so quiet noisy recordings are not boosted into hiss; unlimited when zero
+}
+
+// Validate checks the target
+func (t LoudnessTarget) Validate() error {
+	switch {
+	case t.Integrated >= 0 || math.IsNaN(t.Integrated):
+		return fmt.Errorf("invalid loudness target: integrated loudness must be below 0 LUFS, got %v", t.Integrated)
+	case t.TruePeak > 0 || math.IsNaN(t.TruePeak):
+		return fmt.Errorf("invalid loudness target: true peak ceiling must not exceed 0 dBTP, got %v", t.TruePeak)
+	case t.MaxGain < 0:
+		return fmt.Errorf("invalid loudness target: max gain must not be negative")
+	}
+
+	return nil
+}
+
+// MeasureLoudness measures the loudness of an audio file or stream. See
+// Task.MeasureLoudness; MeasureLoudness uses a Task with the default options and
+// format as its Input.
+//
+// Example:
+//
+//	loudness, err := sox.MeasureLoudness(ctx, "prompt.wav", sox.AudioFormat{})
+//	if err != nil {
+//		return err
+//	}
+//	log.Printf("%.1f LUFS, LRA %.1f LU, %.1f dBTP", loudness.Integrated, loudness.Range, loudness.TruePeak)
+func MeasureLoudness(ctx context.Context, input interface{}, format AudioFormat) (*Loudness, error) {
+	return New(format, format).MeasureLoudness(ctx, input)
+}
+
+// MeasureLoudness measures the integrated loudness, loudness range and true peak
+// of an audio file or stream in the Input format, following ITU-R BS.1770-4 and
+// EBU Tech 3342. sox decodes the input (a path or an io.Reader) to PCM at its
+// sample rate, keeping the channels apart as BS.1770 weights them; the rate and
+// channel count come from the Input format or from probing a path, so readers
+// need Input.Channels.
+//
+// The decoding runs Options.SoxPath, honors Options.Timeout and goes through the
+// Task circuit breaker. Options.Effects are not applied.
+//
+// Example:
+//
+//	task := sox.New(sox.PCM_RAW_8K_MONO, sox.PCM_RAW_8K_MONO)
+//	loudness, err := task.MeasureLoudness(ctx, bytes.NewReader(pcm))
+//	if err != nil {
+//		return err
+//	}
+//	if loudness.Integrated < -30 {
+//		log.Printf("prompt too quiet: %.1f LUFS", loudness.Integrated)
+//	}
+func (c *Task) MeasureLoudness(ctx context.Context, input interface{}) (*Loudness, error) {
+	rate, channels, err := c.inputLayout(ctx, "loudness measurement", input, true)
+	if err != nil {
+		return nil, err
+	}
+
+	meter := newLoudnessMeter(rate, channels)
+
+	if err := c.decodePCM(ctx, "loudness measurement", input, rate, channels, meter.add); err != nil {
+		return nil, err
+	}
+
+	return meter.result(), nil
+}
+
+// NormalizeLoudness converts input to output, like ConvertWithContext, with the
+// gain that brings the input to the target integrated loudness. The input is
+// measured first with MeasureLoudness; readers are spooled to a temporary file so
+// they can be read twice. When the gain would push the true peak above the
+// ceiling, the sox gain limiter (gain -l) holds the peaks below it. Silent input
+// is converted without gain.
+//
+// The gain and limiter run after Options.Effects, which should not change the
+// level (norm, vol, compand), and Options.Norm is ignored. The measurement of the
+// input is returned; the gain applied is target.Integrated minus
+// Loudness.Integrated, capped by MaxGain.
+//
+// Example:
+//
+//	task := sox.New(sox.AudioFormat{Type: sox.TYPE_WAV}, sox.ULAW_8K_MONO)
+//	measured, err := task.NormalizeLoudness(ctx, "upload.wav", "prompt.ul", sox.LoudnessTarget{
+//		Integrated: -16,
+//		TruePeak:   -1,
+//		MaxGain:    20,
+//	})
+//	if err != nil {
+//		return err
+//	}
+//	log.Printf("normalized from %.1f LUFS", measured.Integrated)
+func (c *Task) NormalizeLoudness(ctx context.Context, input, output interface{}, target LoudnessTarget) (*Loudness, error) {
+	if err := target.Validate(); err != nil {
+		return nil, err
+	}
+
+	if r, ok := input.(io.Reader); ok {
+		spooled, err := spoolInput(r)
+		if err != nil {
+			return nil, err
+		}
+		defer os.Remove(spooled)
+
+		input = spooled
+	}
+
+	measured, err := c.MeasureLoudness(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := c.Options
+	opts.Norm = false
+	opts.Effects = append(append([]string(nil), c.Options.Effects...), measured.gainEffects(target)...)
+
+	task := New(c.Input, c.Output).WithOptions(opts)
+	task.circuitBreaker = c.circuitBreaker
+	task.retryConfig = c.retryConfig
+	task.encoder = c.encoder
+
+	if err := task.ConvertWithContext(ctx, input, output); err != nil {
+		return nil, err
+	}
+
+	return measured, nil
+}
+
+// gainEffects returns the sox effects bringing this loudness to the target
+func (l *Loudness) gainEffects(target LoudnessTarget) []string {
+	if math.IsInf(l.Integrated, -1) {
+		return nil
+	}
+
+	gain := target.Integrated - l.Integrated
+	if target.MaxGain > 0 {
+		gain = min(gain, target.MaxGain)
+	}
+
+	if l.TruePeak+gain <= target.TruePeak {
+		return []string{"gain", formatDB(gain)}
+	}
+
+	// The limiter engages near full scale: boost to the ceiling at 0 dBFS, limit, then lower
+	return []string{"gain", "-l", formatDB(gain - target.TruePeak), "gain", formatDB(target.TruePeak)}
+}
+
+// formatDB formats a gain in dB for sox
+func formatDB(db float64) string {
+	return strconv.FormatFloat(db, 'f', 2, 64)
+}
+
+// K-weighting filter design (BS.1770-4, Annex 1) for any sample rate, as derived
+// by libebur128: a high shelf modelling the head, then a high pass
+const (
+	shelfFrequency = 1681.974450955533
+	shelfGain      = 3.999843853973347
+	shelfQ         = 0.7071752369554196
+	highPassFreq   = 38.13547087602444
+	highPassQ      = 0.5003270373238773
+)
+
+// Gating constants of BS.1770 and EBU Tech 3342, in 100ms steps
+const (
+	loudnessStep        = 10 // Steps per second
+	momentarySteps      = 4  // 400ms gating blocks, 75% overlap
+	shortTermSteps      = 30 // 3s short-term blocks for the loudness range
+	absoluteGate        = -70.0
+	relativeGate        = -10.0
+	rangeRelativeGate   = -20.0
+	rangeLowPercentile  = 0.10
+	rangeHighPercentile = 0.95
+)
+
+// truePeakPhases is the oversampling factor of the true peak meter
+const truePeakPhases = 4
+
+// truePeakTaps is the length of each polyphase interpolation filter
+const truePeakTaps = 12
+
+// truePeakFilter holds the interpolation filter of each phase: Hann-windowed sinc,
+// each phase normalized to unity gain. Phase 0 is the input sample itself.
+var truePeakFilter = func() [truePeakPhases][truePeakTaps]float64 {
+	var filter [truePeakPhases][truePeakTaps]float64
+	half := float64(truePeakTaps) / 2
+
+	for p := 0; p < truePeakPhases; p++ {
+		sum := 0.0
+		for k := 0; k < truePeakTaps; k++ {
+			u := half - float64(k) - float64(p)/truePeakPhases
+			h := 1.0
+			if u != 0 {
+				h = math.Sin(math.Pi*u) / (math.Pi * u)
+			}
+			h *= 0.5 + 0.5*math.Cos(math.Pi*u/(half+1))
+
+			filter[p][k] = h
+			sum += h
+		}
+
+		for k := range filter[p] {
+			filter[p][k] /= sum
+		}
+	}
+
+	return filter
+}()
+
+// biquad is a second order IIR filter in transposed direct form II
+type biquad struct {
+	b0, b1, b2, a1, a2 float64
+	z1, z2             float64
+}
+
+func (f *biquad) process(x float64) float64 {
+	y := f.b0*x + f.z1
+	f.z1 = f.b1*x - f.a1*y + f.z2
+	f.z2 = f.b2*x - f.a2*y
+
+	return y
+}
+
+// kWeighting returns the two K-weighting stages for a sample rate
+func kWeighting(rate int) [2]biquad {
+	k := math.Tan(math.Pi * shelfFrequency / float64(rate))
+	vh := math.Pow(10, shelfGain/20)
+	vb := math.Pow(vh, 0.4996667741545416)
+	a0 := 1 + k/shelfQ + k*k
+
+	shelf := biquad{
+		b0: (vh + vb*k/shelfQ + k*k) / a0,
+		b1: 2 * (k*k - vh) / a0,
+		b2: (vh - vb*k/shelfQ + k*k) / a0,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/shelfQ + k*k) / a0,
+	}
+
+	k = math.Tan(math.Pi * highPassFreq / float64(rate))
+	a0 = 1 + k/highPassQ + k*k
+
+	highPass := biquad{
+		b0: 1,
+		b1: -2,
+		b2: 1,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/highPassQ + k*k) / a0,
+	}
+
+	return [2]biquad{shelf, highPass}
+}
+
+// channelWeights returns the BS.1770 weight of each channel: surround channels of
+// 5.0 and 5.1 layouts count 1.41, the 5.1 LFE channel is ignored
+func channelWeights(channels int) []float64 {
+	switch channels {
+	case 5:
+		return []float64{1, 1, 1, 1.41, 1.41}
+	case 6:
+		return []float64{1, 1, 1, 0, 1.41, 1.41}
+	}
+
+	weights := make([]float64, channels)
+	for i := range weights {
+		weights[i] = 1
+	}
+
+	return weights
+}
+
+// loudnessMeter accumulates K-weighted energy in 100ms steps and the true peak
+type loudnessMeter struct {
+	channels  int
+	weights   []float64
+	filters   [][2]biquad
+	stepSize  int       // Frames per 100ms step
+	filled    int       // Frames in the current step
+	sums      []float64 // K-weighted sum of squares of the current step, per channel
+	steps     []float64 // Weighted mean square of each complete step
+	history   [][truePeakTaps]float64
+	truePeak  float64 // Highest absolute interpolated sample
+	haveFrame bool
+}
+
+// newLoudnessMeter returns a meter for interleaved audio
+func newLoudnessMeter(rate, channels int) *loudnessMeter {
+	m := &loudnessMeter{
+		channels: channels,
+		weights:  channelWeights(channels),
+		filters:  make([][2]biquad, channels),
+		stepSize: max(1, (rate+loudnessStep/2)/loudnessStep),
+		sums:     make([]float64, channels),
+		history:  make([][truePeakTaps]float64, channels),
+	}
+
+	for ch := range m.filters {
+		m.filters[ch] = kWeighting(rate)
+	}
+
+	return m
+}
+
+// add accumulates interleaved samples of whole frames
+func (m *loudnessMeter) add(samples []float32) {
+	for i := 0; i+m.channels <= len(samples); i += m.channels {
+		for ch := 0; ch < m.channels; ch++ {
+			x := float64(samples[i+ch])
+
+			y := m.filters[ch][0].process(x)
+			y = m.filters[ch][1].process(y)
+			m.sums[ch] += y * y
+
+			m.addTruePeak(ch, x)
+		}
+
+		m.haveFrame = true
+		m.filled++
+		if m.filled == m.stepSize {
+			m.closeStep()
+		}
+	}
+}
+
+// addTruePeak interpolates between the latest samples of a channel
+func (m *loudnessMeter) addTruePeak(ch int, x float64) {
+	h := &m.history[ch]
+	copy(h[1:], h[:truePeakTaps-1])
+	h[0] = x
+
+	m.truePeak = max(m.truePeak, math.Abs(x))
+
+	for p := 1; p < truePeakPhases; p++ {
+		y := 0.0
+		for k, tap := range truePeakFilter[p] {
+			y += tap * h[k]
+		}
+		m.truePeak = max(m.truePeak, math.Abs(y))
+	}
+}
+
+// closeStep records the weighted mean square of the current step
+func (m *loudnessMeter) closeStep() {
+	energy := 0.0
+	for ch := 0; ch < m.channels; ch++ {
+		energy += m.weights[ch] * m.sums[ch] / float64(m.filled)
+		m.sums[ch] = 0
+	}
+
+	m.steps = append(m.steps, energy)
+	m.filled = 0
+}
+
+// result computes the gated loudness figures. Incomplete 100ms steps at the end
+// are left out, as BS.1770 only measures complete gating blocks.
+func (m *loudnessMeter) result() *Loudness {
+	loudness := &Loudness{
+		Integrated: math.Inf(-1),
+		TruePeak:   math.Inf(-1),
+	}
+
+	if m.haveFrame {
+		loudness.TruePeak = 20 * math.Log10(m.truePeak)
+	}
+
+	blocks := m.blocks(momentarySteps)
+	loudness.Integrated = meanLoudness(blocks, gateThreshold(blocks, relativeGate))
+
+	// Loudness range: spread of the gated short-term loudness
+	shortTerm := m.blocks(shortTermSteps)
+	threshold := gateThreshold(shortTerm, rangeRelativeGate)
+
+	var levels []float64
+	for _, energy := range shortTerm {
+		if level := energyLoudness(energy); level > threshold {
+			levels = append(levels, level)
+		}
+	}
+
+	if len(levels) > 1 {
+		sort.Float64s(levels)
+		loudness.Range = percentile(levels, rangeHighPercentile) - percentile(levels, rangeLowPercentile)
+	}
+
+	return loudness
+}
+
+// blocks returns the mean energy of each run of n consecutive steps
+func (m *loudnessMeter) blocks(n int) []float64 {
+	if len(m.steps) < n {
+		return nil
+	}
+
+	blocks := make([]float64, 0, len(m.steps)-n+1)
+	sum := 0.0
+	for i, energy := range m.steps {
+		sum += energy
+		if i >= n {
+			sum -= m.steps[i-n]
+		}
+		if i >= n-1 {
+			blocks = append(blocks, max(0, sum)/float64(n))
+		}
+	}
+
+	return blocks
+}
+
+// gateThreshold returns the loudness blocks must exceed: the absolute gate, raised
+// to the loudness of the blocks passing it plus the relative gate
+func gateThreshold(blocks []float64, relative float64) float64 {
+	absolute := meanLoudness(blocks, absoluteGate)
+	if math.IsInf(absolute, -1) {
+		return absoluteGate
+	}
+
+	return max(absoluteGate, absolute+relative)
+}
+
+// meanLoudness returns the loudness of the mean energy of the blocks louder than
+// threshold, or -Inf when there are none
+func meanLoudness(blocks []float64, threshold float64) float64 {
+	sum, n := 0.0, 0
+	for _, energy := range blocks {
+		if energyLoudness(energy) > threshold {
+			sum += energy
+			n++
+		}
+	}
+
+	if n == 0 {
+		return math.Inf(-1)
+	}
+
+	return energyLoudness(sum / float64(n))
+}
+
+// energyLoudness converts a weighted mean square to LUFS
+func energyLoudness(energy float64) float64 {
+	return -0.691 + 10*math.Log10(energy)
+}
+
+// percentile returns the nearest-rank percentile of sorted values
+func percentile(sorted []float64, p float64) float64 {
+	return sorted[int(p*float64(len(sorted)-1)+0.5)]
+}
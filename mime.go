@@ -0,0 +1,291 @@
+package sox
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrUnsupportedContentType is returned when a MIME type has no matching audio format
+var ErrUnsupportedContentType = errors.New("content type not supported")
+
+// octetStream is the content type of audio without a registered MIME type
+const octetStream = "application/octet-stream"
+
+// typeMIME holds the MIME type and file extension of each file type
+var typeMIME = map[string]struct {
+	contentType string
+	extension   string
+}{
+	TYPE_RAW:  {octetStream, ".raw"},
+	TYPE_WAV:  {"audio/wav", ".wav"},
+	TYPE_FLAC: {"audio/flac", ".flac"},
+	TYPE_MP3:  {"audio/mpeg", ".mp3"},
+	TYPE_OGG:  {"audio/ogg", ".ogg"},
+	TYPE_OPUS: {"audio/ogg;codecs=opus", ".opus"},
+	TYPE_M4A:  {"audio/mp4", ".m4a"},
+	TYPE_AAC:  {"audio/aac", ".aac"},
+	TYPE_AC3:  {"audio/ac3", ".ac3"},
+	TYPE_EAC3: {"audio/eac3", ".eac3"},
+	TYPE_AIFF: {"audio/aiff", ".aiff"},
+	TYPE_AU:   {"audio/x-au", ".au"},
+	TYPE_GSM:  {"audio/GSM", ".gsm"},
+	TYPE_ALAW: {"audio/PCMA", ".al"},
+
+	// Encodings named as types have no container of their own
+	TYPE_IMA_ADPCM:      {octetStream, ".raw"},
+	TYPE_MS_ADPCM:       {octetStream, ".raw"},
+	TYPE_GSM_FULL_RATE:  {octetStream, ".raw"},
+	TYPE_GSM_HALF_RATE:  {octetStream, ".raw"},
+	TYPE_GSM_EFR_RATE:   {octetStream, ".raw"},
+	TYPE_GSM_FR_RATE:    {octetStream, ".raw"},
+	TYPE_GSM_HR_RATE:    {octetStream, ".raw"},
+	TYPE_GSM_MR_RATE:    {octetStream, ".raw"},
+	TYPE_GSM_SUPER_RATE: {octetStream, ".raw"},
+}
+
+// contentTypeAliases maps MIME types, including common unregistered ones, to file types
+var contentTypeAliases = map[string]string{
+	"audio/wav":                TYPE_WAV,
+	"audio/wave":               TYPE_WAV,
+	"audio/x-wav":              TYPE_WAV,
+	"audio/vnd.wave":           TYPE_WAV,
+	"audio/flac":               TYPE_FLAC,
+	"audio/x-flac":             TYPE_FLAC,
+	"audio/mpeg":               TYPE_MP3,
+	"audio/mp3":                TYPE_MP3,
+	"audio/mpeg3":              TYPE_MP3,
+	"audio/x-mpeg":             TYPE_MP3,
+	"audio/ogg":                TYPE_OGG,
+	"audio/vorbis":             TYPE_OGG,
+	"application/ogg":          TYPE_OGG,
+	"audio/opus":               TYPE_OPUS,
+	"audio/mp4":                TYPE_M4A,
+	"audio/m4a":                TYPE_M4A,
+	"audio/x-m4a":              TYPE_M4A,
+	"audio/aac":                TYPE_AAC,
+	"audio/aacp":               TYPE_AAC,
+	"audio/x-aac":              TYPE_AAC,
+	"audio/ac3":                TYPE_AC3,
+	"audio/eac3":               TYPE_EAC3,
+	"audio/aiff":               TYPE_AIFF,
+	"audio/x-aiff":             TYPE_AIFF,
+	"audio/au":                 TYPE_AU,
+	"audio/x-au":               TYPE_AU,
+	"audio/x-gsm":              TYPE_GSM,
+	"application/octet-stream": TYPE_RAW,
+}
+
+// ContentType returns the MIME type of audio in this format, for HTTP Content-Type
+// headers. Raw formats matching an RTP payload format get its MIME type with rate
+// and channel parameters (RFC 2586, RFC 4856), 8 kHz mono mu-law is "audio/basic"
+// and other raw audio is "application/octet-stream".
+//
+// Example:
+//
+//	sox.FLAC_16K_MONO.ContentType() // "audio/flac"
+//	sox.L16_16K_MONO.ContentType()  // "audio/L16;rate=16000;channels=1"
+//	sox.ULAW_8K_MONO.ContentType()  // "audio/basic"
+//
+//	w.Header().Set("Content-Type", task.Output.ContentType())
+func (f *AudioFormat) ContentType() string {
+	typ := strings.ToLower(f.Type)
+
+	if typ == TYPE_RAW || typ == TYPE_ALAW {
+		return f.rawContentType(typ)
+	}
+
+	if m, ok := typeMIME[typ]; ok {
+		return m.contentType
+	}
+
+	return octetStream
+}
+
+// rawContentType returns the MIME type of the RTP payload format matching a raw format,
+// or the MIME type of the raw type when there is none
+func (f *AudioFormat) rawContentType(typ string) string {
+	raw := *f
+	raw.Type = TYPE_RAW
+	raw.Encoding = raw.Encoding.Canonical()
+
+	if typ == TYPE_ALAW {
+		raw.Encoding = A_LAW
+	}
+
+	codec, err := raw.rtpCodec()
+	if err != nil {
+		return typeMIME[typ].contentType
+	}
+
+	if codec.name == "PCMU" && codec.clock == 8000 && codec.channels == 1 {
+		return "audio/basic"
+	}
+
+	return fmt.Sprintf("audio/%s;rate=%d;channels=%d", codec.name, codec.clock, codec.channels)
+}
+
+// Extension returns the file extension for this format, including the leading dot.
+// Raw mu-law and A-law use ".ul" and ".al", and types without a known extension
+// use the type name, as sox does.
+//
+// Example:
+//
+//	name := "recording" + sox.FLAC_16K_MONO.Extension() // "recording.flac"
+func (f *AudioFormat) Extension() string {
+	typ := strings.ToLower(f.Type)
+
+	if typ == TYPE_RAW {
+		switch f.Encoding.Canonical() {
+		case MU_LAW:
+			return ".ul"
+		case A_LAW:
+			return ".al"
+		}
+	}
+
+	if m, ok := typeMIME[typ]; ok {
+		return m.extension
+	}
+
+	if typ == "" {
+		return typeMIME[TYPE_RAW].extension
+	}
+
+	return "." + typ
+}
+
+// FormatFromContentType returns the AudioFormat for a single MIME type such as an
+// HTTP Content-Type or one Accept entry; use FormatFromAccept for a whole Accept
+// header. Parameters are honored: rate and channels for audio/L8, audio/L16,
+// audio/L24, audio/PCMU, audio/PCMA and audio/GSM, and codecs=opus for audio/ogg.
+// Container types only set Type, as rate and channels are read from the file header.
+//
+// Example:
+//
+//	f, err := sox.FormatFromContentType("audio/L16;rate=16000;channels=1")
+//	// f: raw, signed-integer 16-bit big-endian, 16000 Hz, mono
+//
+//	f, err = sox.FormatFromContentType("audio/basic") // raw mu-law, 8000 Hz, mono
+//
+//	_, err = sox.FormatFromContentType("video/mp4")
+//	// errors.Is(err, sox.ErrUnsupportedContentType) == true
+func FormatFromContentType(contentType string) (AudioFormat, error) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return AudioFormat{}, fmt.Errorf("invalid content type %q: %w", contentType, err)
+	}
+
+	switch mediaType {
+	case "audio/basic":
+		return rtpCodec{name: "PCMU", clock: 8000, channels: 1}.format()
+	case "audio/l8", "audio/l16", "audio/l24", "audio/pcmu", "audio/pcma", "audio/gsm":
+		return payloadFromContentType(contentType, mediaType, params)
+	case "audio/ogg", "application/ogg":
+		if strings.Contains(strings.ToLower(params["codecs"]), "opus") {
+			return AudioFormat{Type: TYPE_OPUS}, nil
+		}
+	}
+
+	if typ, ok := contentTypeAliases[mediaType]; ok {
+		return AudioFormat{Type: typ}, nil
+	}
+
+	return AudioFormat{}, fmt.Errorf("%w: %q", ErrUnsupportedContentType, contentType)
+}
+
+// FormatFromAccept returns the AudioFormat of the preferred supported media type
+// of an HTTP Accept header: entries are ordered by their q value, keeping the
+// header order for equal values, and the first one FormatFromContentType accepts
+// wins. Wildcards such as audio/* and entries with q=0 are skipped. It returns an
+// error matching ErrUnsupportedContentType when no entry is supported.
+//
+// Example:
+//
+//	f, err := sox.FormatFromAccept("audio/webm;codecs=opus, audio/ogg;codecs=opus;q=0.8, */*;q=0.1")
+//	// f.Type == "opus": sox cannot write webm
+func FormatFromAccept(accept string) (AudioFormat, error) {
+	type entry struct {
+		contentType string
+		q           float64
+	}
+
+	var entries []entry
+	for _, part := range splitAccept(accept) {
+		mediaType, params, err := mime.ParseMediaType(part)
+		if err != nil || strings.Contains(mediaType, "*") {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+
+		if q > 0 {
+			entries = append(entries, entry{part, q})
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].q > entries[j].q
+	})
+
+	for _, e := range entries {
+		if f, err := FormatFromContentType(e.contentType); err == nil {
+			return f, nil
+		}
+	}
+
+	return AudioFormat{}, fmt.Errorf("%w: no supported type in %q", ErrUnsupportedContentType, accept)
+}
+
+// splitAccept splits an Accept header on the commas between entries, leaving
+// commas in quoted parameter values such as codecs="opus, vorbis" alone
+func splitAccept(accept string) []string {
+	var parts []string
+
+	quoted, start := false, 0
+	for i, r := range accept {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case r == ',' && !quoted:
+			parts = append(parts, strings.TrimSpace(accept[start:i]))
+			start = i + 1
+		}
+	}
+
+	return append(parts, strings.TrimSpace(accept[start:]))
+}
+
+// payloadFromContentType returns the format of an RTP payload MIME type. Linear PCM
+// requires a rate parameter (RFC 2586); G.711 and GSM default to 8000 Hz.
+func payloadFromContentType(contentType, mediaType string, params map[string]string) (AudioFormat, error) {
+	codec := rtpCodec{name: strings.ToUpper(strings.TrimPrefix(mediaType, "audio/")), clock: 8000, channels: 1}
+
+	if rate, ok := params["rate"]; ok {
+		clock, err := strconv.Atoi(rate)
+		if err != nil || clock <= 0 {
+			return AudioFormat{}, fmt.Errorf("invalid content type %q: bad rate", contentType)
+		}
+		codec.clock = clock
+	} else if strings.HasPrefix(codec.name, "L") {
+		return AudioFormat{}, fmt.Errorf("invalid content type %q: %s requires a rate", contentType, codec.name)
+	}
+
+	if channels, ok := params["channels"]; ok {
+		n, err := strconv.Atoi(channels)
+		if err != nil || n <= 0 {
+			return AudioFormat{}, fmt.Errorf("invalid content type %q: bad channel count", contentType)
+		}
+		codec.channels = n
+	}
+
+	return codec.format()
+}
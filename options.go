@@ -7,7 +7,8 @@ import (
 
 // ConversionOptions provides additional options for audio conversion
 type ConversionOptions struct {
-	// SoxPath specifies the path to the sox binary (defaults to "sox")
+	// SoxPath specifies the sox binary, as a path or a command name.
+	// When empty (the default), sox is discovered, see FindSox.
 	SoxPath string
 
 	// BufferSize sets the buffer size for I/O operations (defaults to 32KB)
@@ -18,10 +19,15 @@ type ConversionOptions struct {
 	Effects []string
 
 	// Quality sets compression quality for lossy formats (0-10, higher is better)
-	// Only applicable for certain formats like MP3, OGG
+	// Applied as the Vorbis quality of ogg outputs
+	//
+	// Deprecated: use Task.WithEncoder with VorbisSettings or MP3Settings.
 	Quality int
 
 	// CompressionLevel sets compression level for lossless formats like FLAC (0-8)
+	// Applied as the FLAC compression level of flac outputs
+	//
+	// Deprecated: use Task.WithEncoder with FLACSettings.
 	CompressionLevel int
 
 	// ShowProgress enables progress output from SoX (written to stderr)
@@ -33,6 +39,11 @@ type ConversionOptions struct {
 	// Timeout sets maximum duration for conversion (0 = no timeout)
 	Timeout time.Duration
 
+	// FailOnClip fails conversions in which sox clipped samples with ErrClipped.
+	// The output is still written; failed runs are not retried and do not count
+	// against the circuit breaker. See Task.WithReportHandler.
+	FailOnClip bool
+
 	// Global SoX options (gopts)
 	Buffer         int    // --buffer BYTES - Set the size of all processing buffers (default 8192)
 	NoClobber      bool   // --no-clobber - Prompt to overwrite output file
@@ -61,7 +72,7 @@ type ConversionOptions struct {
 // DefaultOptions returns ConversionOptions with sensible defaults
 func DefaultOptions() ConversionOptions {
 	return ConversionOptions{
-		SoxPath:          "sox",
+		SoxPath:          "",
 		BufferSize:       32 * 1024, // 32KB
 		Quality:          -1,        // not set
 		CompressionLevel: -1,        // not set
@@ -171,13 +182,6 @@ func (o *ConversionOptions) BuildGlobalArgs() []string {
 		args = append(args, o.CustomGlobalArgs...)
 	}
 
-	if o.CompressionLevel >= 0 {
-		args = append(args, "-C", fmt.Sprintf("%d", o.CompressionLevel))
-	}
-
-	if o.Quality >= 0 {
-		args = append(args, "-q", fmt.Sprintf("%d", o.Quality))
-	}
 	return args
 }
 
@@ -0,0 +1,359 @@
+package sox
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// ErrInvalidWaveform is returned when waveform data is malformed or inconsistent
+var ErrInvalidWaveform = errors.New("invalid waveform")
+
+// defaultSamplesPerPixel is the audiowaveform default zoom
+const defaultSamplesPerPixel = 256
+
+// PeaksOptions configures waveform peak computation
+type PeaksOptions struct {
+	SamplesPerPixel int  // Samples per channel in each min/max pair; 256 when zero and PixelsPerSecond is unset
+	PixelsPerSecond int  // Min/max pairs per second, an alternative to SamplesPerPixel
+	Bits            int  // Resolution of the peaks, 8 or 16; 16 when zero
+	PerChannel      bool // Peaks for each channel; otherwise the channels are mixed down
+}
+
+// Validate checks the options
+func (o PeaksOptions) Validate() error {
+	switch {
+	case o.SamplesPerPixel < 0 || o.PixelsPerSecond < 0:
+		return fmt.Errorf("invalid peaks options: resolution must not be negative")
+	case o.SamplesPerPixel > 0 && o.PixelsPerSecond > 0:
+		return fmt.Errorf("invalid peaks options: set SamplesPerPixel or PixelsPerSecond, not both")
+	case o.Bits != 0 && o.Bits != 8 && o.Bits != 16:
+		return fmt.Errorf("invalid peaks options: bits must be 8 or 16, got %d", o.Bits)
+	}
+
+	return nil
+}
+
+// Waveform holds min/max peak pairs in the layout of audiowaveform (version 2), so
+// it can be served to web players such as peaks.js as .json or .dat
+type Waveform struct {
+	Version         int     `json:"version"`           // Format version, 2 (1 when read from a version 1 file)
+	Channels        int     `json:"channels"`          // Number of channels
+	SampleRate      int     `json:"sample_rate"`       // Sample rate of the decoded audio
+	SamplesPerPixel int     `json:"samples_per_pixel"` // Samples per channel in each pair
+	Bits            int     `json:"bits"`              // 8 or 16
+	Length          int     `json:"length"`            // Pairs per channel
+	Data            []int16 `json:"data"`              // Pairs in time order: min and max of each channel in turn
+}
+
+// Duration returns the length of the audio covered by the waveform
+func (w *Waveform) Duration() time.Duration {
+	if w.SampleRate <= 0 {
+		return 0
+	}
+
+	return samplesDuration(int64(w.Length)*int64(w.SamplesPerPixel), w.SampleRate)
+}
+
+// Peak returns the min and max values of a channel (from 0) at a pair index
+func (w *Waveform) Peak(channel, index int) (int16, int16) {
+	i := 2 * (index*w.Channels + channel)
+	return w.Data[i], w.Data[i+1]
+}
+
+// Validate checks that the header fields are usable and match the data
+func (w *Waveform) Validate() error {
+	switch {
+	case w.Channels < 1:
+		return fmt.Errorf("%w: %d channels", ErrInvalidWaveform, w.Channels)
+	case w.Bits != 8 && w.Bits != 16:
+		return fmt.Errorf("%w: %d bits", ErrInvalidWaveform, w.Bits)
+	case w.SampleRate <= 0 || w.SamplesPerPixel <= 0:
+		return fmt.Errorf("%w: sample rate %d, %d samples per pixel", ErrInvalidWaveform, w.SampleRate, w.SamplesPerPixel)
+	case len(w.Data) != 2*w.Length*w.Channels:
+		return fmt.Errorf("%w: %d values for %d pairs of %d channels", ErrInvalidWaveform, len(w.Data), w.Length, w.Channels)
+	}
+
+	return nil
+}
+
+// waveformHeader is the header of an audiowaveform .dat file, all fields little-endian
+type waveformHeader struct {
+	Version         int32
+	Flags           uint32 // Bit 0 set for 8-bit data
+	SampleRate      int32
+	SamplesPerPixel int32
+	Length          uint32
+}
+
+// MarshalBinary encodes the waveform as an audiowaveform .dat file: version 2, or
+// version 1 when Version is 1 and there is a single channel.
+//
+// Example:
+//
+//	dat, err := waveform.MarshalBinary()
+//	if err != nil {
+//		return err
+//	}
+//	os.WriteFile("call.dat", dat, 0644)
+func (w *Waveform) MarshalBinary() ([]byte, error) {
+	if err := w.Validate(); err != nil {
+		return nil, err
+	}
+
+	version := int32(2)
+	if w.Version == 1 {
+		if w.Channels != 1 {
+			return nil, fmt.Errorf("%w: version 1 supports a single channel", ErrInvalidWaveform)
+		}
+		version = 1
+	}
+
+	header := waveformHeader{
+		Version:         version,
+		SampleRate:      int32(w.SampleRate),
+		SamplesPerPixel: int32(w.SamplesPerPixel),
+		Length:          uint32(w.Length),
+	}
+	if w.Bits == 8 {
+		header.Flags = 1
+	}
+
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, header)
+	if version == 2 {
+		binary.Write(&buf, binary.LittleEndian, int32(w.Channels))
+	}
+
+	if w.Bits == 8 {
+		for _, v := range w.Data {
+			buf.WriteByte(byte(int8(v)))
+		}
+	} else {
+		binary.Write(&buf, binary.LittleEndian, w.Data)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary decodes an audiowaveform .dat file, version 1 or 2
+func (w *Waveform) UnmarshalBinary(data []byte) error {
+	r := bytes.NewReader(data)
+
+	var header waveformHeader
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return fmt.Errorf("%w: short header", ErrInvalidWaveform)
+	}
+
+	decoded := Waveform{
+		Version:         int(header.Version),
+		Channels:        1,
+		SampleRate:      int(header.SampleRate),
+		SamplesPerPixel: int(header.SamplesPerPixel),
+		Bits:            16,
+		Length:          int(header.Length),
+	}
+
+	switch header.Version {
+	case 1:
+	case 2:
+		var channels int32
+		if err := binary.Read(r, binary.LittleEndian, &channels); err != nil {
+			return fmt.Errorf("%w: short header", ErrInvalidWaveform)
+		}
+		decoded.Channels = int(channels)
+	default:
+		return fmt.Errorf("%w: unsupported version %d", ErrInvalidWaveform, header.Version)
+	}
+
+	if header.Flags&1 != 0 {
+		decoded.Bits = 8
+	}
+
+	// Validate the counts against the data size before allocating
+	values := r.Len()
+	if decoded.Bits == 16 {
+		values /= 2
+	}
+	if decoded.Channels < 1 || int64(values) != 2*int64(decoded.Length)*int64(decoded.Channels) {
+		return fmt.Errorf("%w: %d bytes of data for %d pairs of %d channels", ErrInvalidWaveform, r.Len(), decoded.Length, decoded.Channels)
+	}
+
+	decoded.Data = make([]int16, values)
+	if decoded.Bits == 8 {
+		for i := range decoded.Data {
+			b, _ := r.ReadByte()
+			decoded.Data[i] = int16(int8(b))
+		}
+	} else if err := binary.Read(r, binary.LittleEndian, decoded.Data); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidWaveform, err)
+	}
+
+	if err := decoded.Validate(); err != nil {
+		return err
+	}
+
+	*w = decoded
+	return nil
+}
+
+// UnmarshalJSON decodes an audiowaveform .json file. Version 1 files, which have
+// no channel count, are single channel.
+func (w *Waveform) UnmarshalJSON(data []byte) error {
+	type plain Waveform
+
+	decoded := plain{Version: 1, Channels: 1}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+
+	waveform := Waveform(decoded)
+	if err := waveform.Validate(); err != nil {
+		return err
+	}
+
+	*w = waveform
+	return nil
+}
+
+// Peaks computes waveform peaks of an audio file or stream. See Task.Peaks; Peaks
+// uses a Task with the default options and format as its Input.
+//
+// Example:
+//
+//	waveform, err := sox.Peaks(ctx, "call.wav", sox.AudioFormat{}, sox.PeaksOptions{PixelsPerSecond: 50, Bits: 8})
+//	if err != nil {
+//		return err
+//	}
+//	json.NewEncoder(w).Encode(waveform) // audiowaveform JSON for peaks.js
+func Peaks(ctx context.Context, input interface{}, format AudioFormat, options PeaksOptions) (*Waveform, error) {
+	return New(format, format).Peaks(ctx, input, options)
+}
+
+// Peaks computes the min and max sample of each block of SamplesPerPixel samples,
+// like audiowaveform, for drawing waveforms in web players. sox decodes the input
+// (a path or an io.Reader in the Input format) to PCM at its sample rate, which
+// comes from the Input format or from probing a path; readers without
+// Input.SampleRate are decoded at 16 kHz. With PixelsPerSecond, the block size is
+// the sample rate divided by it.
+//
+// Channels are mixed down unless PerChannel is set. The last block may be partial.
+// The decoding runs Options.SoxPath, honors Options.Timeout and goes through the
+// Task circuit breaker. Options.Effects are not applied.
+//
+// Example:
+//
+//	task := sox.New(sox.AudioFormat{Type: sox.TYPE_WAV, Channels: 2}, sox.AudioFormat{})
+//	waveform, err := task.Peaks(ctx, upload, sox.PeaksOptions{SamplesPerPixel: 512, PerChannel: true})
+//	if err != nil {
+//		return err
+//	}
+//	dat, err := waveform.MarshalBinary() // audiowaveform .dat, version 2
+func (c *Task) Peaks(ctx context.Context, input interface{}, options PeaksOptions) (*Waveform, error) {
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+
+	rate, channels, err := c.inputLayout(ctx, "peaks", input, options.PerChannel)
+	if err != nil {
+		return nil, err
+	}
+
+	samplesPerPixel := options.SamplesPerPixel
+	switch {
+	case options.PixelsPerSecond > 0:
+		samplesPerPixel = max(1, rate/options.PixelsPerSecond)
+	case samplesPerPixel == 0:
+		samplesPerPixel = defaultSamplesPerPixel
+	}
+
+	bits := options.Bits
+	if bits == 0 {
+		bits = 16
+	}
+
+	builder := newPeaksBuilder(channels, samplesPerPixel, bits)
+
+	if err := c.decodePCM(ctx, "peaks", input, rate, channels, builder.add); err != nil {
+		return nil, err
+	}
+	builder.finish()
+
+	return &Waveform{
+		Version:         2,
+		Channels:        channels,
+		SampleRate:      rate,
+		SamplesPerPixel: samplesPerPixel,
+		Bits:            bits,
+		Length:          len(builder.data) / (2 * channels),
+		Data:            builder.data,
+	}, nil
+}
+
+// peaksBuilder accumulates the min and max of each block of frames per channel
+type peaksBuilder struct {
+	channels        int
+	samplesPerPixel int
+	shift           uint // Right shift from 16-bit to the target resolution
+	min, max        []int16
+	filled          int // Frames in the current block
+	data            []int16
+}
+
+// newPeaksBuilder returns a builder of blocks of samplesPerPixel frames
+func newPeaksBuilder(channels, samplesPerPixel, bits int) *peaksBuilder {
+	return &peaksBuilder{
+		channels:        channels,
+		samplesPerPixel: samplesPerPixel,
+		shift:           uint(16 - bits),
+		min:             make([]int16, channels),
+		max:             make([]int16, channels),
+		data:            []int16{},
+	}
+}
+
+// add accumulates interleaved samples of whole frames
+func (b *peaksBuilder) add(samples []float32) {
+	for i := 0; i+b.channels <= len(samples); i += b.channels {
+		for ch := 0; ch < b.channels; ch++ {
+			v := toInt16(samples[i+ch]) >> b.shift
+
+			if b.filled == 0 || v < b.min[ch] {
+				b.min[ch] = v
+			}
+			if b.filled == 0 || v > b.max[ch] {
+				b.max[ch] = v
+			}
+		}
+
+		b.filled++
+		if b.filled == b.samplesPerPixel {
+			b.closeBlock()
+		}
+	}
+}
+
+// finish closes the last, partial block
+func (b *peaksBuilder) finish() {
+	if b.filled > 0 {
+		b.closeBlock()
+	}
+}
+
+// closeBlock appends the pairs of the current block and starts a new one
+func (b *peaksBuilder) closeBlock() {
+	for ch := 0; ch < b.channels; ch++ {
+		b.data = append(b.data, b.min[ch], b.max[ch])
+	}
+	b.filled = 0
+}
+
+// toInt16 converts a float sample to 16-bit, clamping values outside full scale
+func toInt16(v float32) int16 {
+	return int16(max(math.MinInt16, min(math.MaxInt16, math.Round(float64(v)*32768))))
+}
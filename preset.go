@@ -0,0 +1,114 @@
+package sox
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// presets holds the built-in and registered formats by normalized name
+var (
+	presetsLock sync.RWMutex
+	presets     = map[string]AudioFormat{
+		"pcm_raw_8k_mono":  PCM_RAW_8K_MONO,
+		"pcm_raw_16k_mono": PCM_RAW_16K_MONO,
+		"ulaw_8k_mono":     ULAW_8K_MONO,
+		"alaw_8k_mono":     ALAW_8K_MONO,
+		"l16_16k_mono":     L16_16K_MONO,
+		"l16_48k_stereo":   L16_48K_STEREO,
+		"gsm_8k_mono":      GSM_8K_MONO,
+		"flac_16k_mono":    FLAC_16K_MONO,
+		"flac_16k_mono_le": FLAC_16K_MONO_LE,
+		"flac_48k_stereo":  FLAC_48K_STEREO,
+		"wav_8k_mono_le":   WAV_8K_MONO_LE,
+		"wav_16k_mono":     WAV_16K_MONO,
+		"wav_16k_mono_le":  WAV_16K_MONO_LE,
+		"wav_44k_stereo":   WAV_44K_STEREO,
+		"wav_48k_stereo":   WAV_48K_STEREO,
+		"mp3_voice":        MP3_VOICE,
+		"mp3_music":        MP3_MUSIC,
+		"ogg_voice":        OGG_VOICE,
+	}
+)
+
+// normalizePresetName makes preset names case-insensitive, with "-" and "_" equivalent
+func normalizePresetName(name string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(name)), "-", "_")
+}
+
+// RegisterPreset adds a named format to the preset registry, so it can be looked
+// up with Preset and by ParseFormat. Names are case-insensitive, with "-" and "_"
+// equivalent. The format must be valid and the name not taken yet.
+//
+// Example:
+//
+//	err := sox.RegisterPreset("call-archive", sox.AudioFormat{
+//		Type: "flac", SampleRate: 8000, Channels: 1, BitDepth: 16,
+//	})
+//
+//	format, ok := sox.Preset("CALL_ARCHIVE")
+func RegisterPreset(name string, format AudioFormat) error {
+	key := normalizePresetName(name)
+	if key == "" {
+		return fmt.Errorf("preset name is empty")
+	}
+
+	if err := format.Validate(); err != nil {
+		return fmt.Errorf("preset %q: %w", name, err)
+	}
+
+	presetsLock.Lock()
+	defer presetsLock.Unlock()
+
+	if _, exists := presets[key]; exists {
+		return fmt.Errorf("preset %q is already registered", name)
+	}
+
+	presets[key] = format.clone()
+	return nil
+}
+
+// Preset returns the built-in or registered format with the given name, e.g.
+// "ULAW_8K_MONO" or "wav-44k-stereo". The returned format is a copy.
+//
+// Example:
+//
+//	output, ok := sox.Preset(cfg.OutputFormat)
+//	if !ok {
+//		return fmt.Errorf("unknown format %q, available: %v", cfg.OutputFormat, sox.Presets())
+//	}
+func Preset(name string) (AudioFormat, bool) {
+	presetsLock.RLock()
+	defer presetsLock.RUnlock()
+
+	format, ok := presets[normalizePresetName(name)]
+	if !ok {
+		return AudioFormat{}, false
+	}
+
+	return format.clone(), true
+}
+
+// Presets returns the names of all built-in and registered presets, sorted
+func Presets() []string {
+	presetsLock.RLock()
+	defer presetsLock.RUnlock()
+
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// clone returns a copy of the format that shares no slices with it
+func (f AudioFormat) clone() AudioFormat {
+	if f.CustomArgs != nil {
+		f.CustomArgs = append([]string(nil), f.CustomArgs...)
+	}
+
+	return f
+}
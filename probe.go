@@ -0,0 +1,352 @@
+package sox
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AudioInfo describes an audio file, as reported by Probe
+type AudioInfo struct {
+	Type       string        // File type, e.g. "wav", "flac", "mp3"
+	Encoding   Encoding      // Sample encoding; empty for codecs such as FLAC, MP3 or Vorbis
+	SampleRate int           // Sample rate in Hz
+	Channels   int           // Number of channels
+	BitDepth   int           // Bits per stored sample; 0 for codecs such as FLAC or MP3
+	Precision  int           // Significant bits per sample, e.g. 16 for a 16-bit FLAC
+	Samples    int64         // Samples per channel, derived from Duration for long files; see ProbeSamples
+	Duration   time.Duration // Playing time
+	Bitrate    int           // Average bits per second over the whole file, header included
+	Size       int64         // File size in bytes
+	Comments   []string      // File comments, usually KEY=value, e.g. "Title=Greeting"
+}
+
+// probeEncodings maps sox sample encoding descriptions to encodings
+var probeEncodings = map[string]Encoding{
+	"Signed Integer PCM":   SIGNED_INTEGER,
+	"Unsigned Integer PCM": UNSIGNED_INTEGER,
+	"Floating Point PCM":   FLOATING_POINT,
+	"u-law":                MU_LAW,
+	"A-law":                A_LAW,
+	"IMA ADPCM":            IMA_ADPCM,
+	"MS ADPCM":             MS_ADPCM,
+	"OKI ADPCM":            OKI_ADPCM,
+	"GSM":                  GSM_FULL_RATE,
+}
+
+// Format returns the probed format, usable as a Task Input.
+//
+// Example:
+//
+//	info, err := sox.Probe(ctx, "upload.wav")
+//	if err != nil {
+//		return err
+//	}
+//	task := sox.New(info.Format(), sox.FLAC_16K_MONO)
+func (i *AudioInfo) Format() AudioFormat {
+	return AudioFormat{
+		Type:       i.Type,
+		Encoding:   i.Encoding,
+		SampleRate: i.SampleRate,
+		Channels:   i.Channels,
+		BitDepth:   i.BitDepth,
+	}
+}
+
+// Probe describes an audio file or stream without converting it, like soxi.
+// See Task.Probe; Probe uses a Task with the default options.
+//
+// Example:
+//
+//	info, err := sox.Probe(ctx, "call.wav")
+//	if err != nil {
+//		return err
+//	}
+//	log.Printf("%s: %d Hz, %d ch, %s", info.Type, info.SampleRate, info.Channels, info.Duration)
+func Probe(ctx context.Context, input interface{}) (*AudioInfo, error) {
+	return New().Probe(ctx, input)
+}
+
+// Probe describes an audio file or stream without converting it, using a single
+// run of sox --i (soxi). The input is a file path or an io.Reader; readers are
+// spooled to a temporary file, named after the sniffed container, since sox reads
+// headers (and sometimes the end of the file) by seeking. Headerless raw audio
+// cannot be probed. The type is sniffed from the file header, or taken from the
+// extension.
+//
+// sox prints sample counts of 1000 or more to three significant figures, so
+// Samples is then derived from the duration, which sox prints to the centisecond.
+// Use ProbeSamples for the exact count.
+//
+// The probe runs Options.SoxPath, honors Options.Timeout and goes through the
+// Task circuit breaker, like conversions.
+//
+// Example:
+//
+//	task := sox.New(sox.AudioFormat{}, sox.FLAC_16K_MONO).WithOptions(opts)
+//	info, err := task.Probe(ctx, upload)
+//	if err != nil {
+//		return err
+//	}
+//	if info.Duration > 10*time.Minute {
+//		return fmt.Errorf("recording too long: %s", info.Duration)
+//	}
+func (c *Task) Probe(ctx context.Context, input interface{}) (*AudioInfo, error) {
+	var info *AudioInfo
+
+	err := c.probeInput(ctx, input, func(ctx context.Context, path string) error {
+		summary, err := c.soxInfo(ctx, "", path)
+		if err != nil {
+			return err
+		}
+
+		info = parseSoxInfo(summary)
+		info.Type = probeType(path)
+
+		if stat, err := os.Stat(path); err == nil {
+			info.Size = stat.Size()
+		}
+
+		switch {
+		case info.SampleRate <= 0:
+		case info.Samples > 0:
+			info.Duration = samplesDuration(info.Samples, info.SampleRate)
+		case info.Duration > 0:
+			info.Samples = int64(math.Round(info.Duration.Seconds() * float64(info.SampleRate)))
+		}
+
+		if info.Duration > 0 {
+			info.Bitrate = int(float64(info.Size) * 8 / info.Duration.Seconds())
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return info, nil
+}
+
+// ProbeSamples returns the exact number of samples per channel of an audio file or
+// stream, with sox --i -s. The input is handled as in Probe.
+//
+// Example:
+//
+//	samples, err := task.ProbeSamples(ctx, "call.wav")
+//	if err != nil {
+//		return err
+//	}
+//	pcm := make([]int16, samples)
+func (c *Task) ProbeSamples(ctx context.Context, input interface{}) (int64, error) {
+	var samples int64
+
+	err := c.probeInput(ctx, input, func(ctx context.Context, path string) error {
+		output, err := c.soxInfo(ctx, "-s", path)
+		if err != nil {
+			return err
+		}
+
+		samples, err = strconv.ParseInt(strings.TrimSpace(string(output)), 10, 64)
+		if err != nil {
+			return fmt.Errorf("failed to parse sample count %q: %w", strings.TrimSpace(string(output)), err)
+		}
+
+		return nil
+	})
+
+	return samples, err
+}
+
+// probeInput runs fn on the path of a probe input, spooling readers to a temporary
+// file, as a guarded sox call
+func (c *Task) probeInput(ctx context.Context, input interface{}, fn func(ctx context.Context, path string) error) error {
+	var path string
+
+	switch in := input.(type) {
+	case string:
+		path = in
+	case io.Reader:
+		spooled, err := spoolInput(in)
+		if err != nil {
+			return err
+		}
+		defer os.Remove(spooled)
+
+		path = spooled
+	default:
+		return fmt.Errorf("unsupported probe input type: %T", input)
+	}
+
+	return c.guardedCall(ctx, func(ctx context.Context) error {
+		return fn(ctx, path)
+	})
+}
+
+// probeType sniffs the type of a file from its header, falling back to its extension
+func probeType(path string) string {
+	file, err := os.Open(path)
+	if err == nil {
+		defer file.Close()
+
+		header := make([]byte, sniffLen)
+		n, _ := io.ReadFull(file, header)
+		if f, ok := DetectFormat(header[:n]); ok {
+			return f.Type
+		}
+	}
+
+	return typeFromExtension(path)
+}
+
+// soxInfo runs sox --i with an optional soxi option and returns its output
+func (c *Task) soxInfo(ctx context.Context, option, path string) ([]byte, error) {
+	args := []string{"--i"}
+	if option != "" {
+		args = append(args, option)
+	}
+	args = append(args, path)
+
+	output := &bytes.Buffer{}
+	if _, err := c.execSox(ctx, "probe", args, nil, output); err != nil {
+		return nil, err
+	}
+
+	return output.Bytes(), nil
+}
+
+// parseSoxInfo parses the summary printed by sox --i. Comments come last, either
+// as a single quoted "Comment" line or as a "Comments" line followed by one
+// comment per line.
+func parseSoxInfo(summary []byte) *AudioInfo {
+	info := &AudioInfo{}
+	inComments := false
+
+	scanner := bufio.NewScanner(bytes.NewReader(summary))
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if inComments {
+			if line != "" {
+				info.Comments = append(info.Comments, line)
+			}
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+
+		switch strings.TrimSpace(key) {
+		case "Channels":
+			info.Channels, _ = strconv.Atoi(value)
+		case "Sample Rate":
+			if rate, err := strconv.ParseFloat(value, 64); err == nil {
+				info.SampleRate = int(rate + 0.5)
+			}
+		case "Precision":
+			info.Precision, _ = strconv.Atoi(strings.TrimSuffix(value, "-bit"))
+		case "Duration":
+			// HH:MM:SS.ss = N samples ~ ..., where N is abbreviated (e.g. "2.65M") from 1000 on
+			fields := strings.Fields(value)
+			if len(fields) > 0 {
+				info.Duration = parseClock(fields[0])
+			}
+			if len(fields) > 2 && fields[1] == "=" {
+				info.Samples, _ = strconv.ParseInt(fields[2], 10, 64)
+			}
+		case "Sample Encoding":
+			info.BitDepth, info.Encoding = parseSampleEncoding(value)
+		case "Comment":
+			info.Comments = append(info.Comments, strings.Trim(value, "'"))
+		case "Comments":
+			inComments = true
+		}
+	}
+
+	return info
+}
+
+// parseSampleEncoding parses a sox encoding description such as "16-bit Signed Integer PCM"
+func parseSampleEncoding(value string) (int, Encoding) {
+	bits := 0
+
+	if prefix, rest, ok := strings.Cut(value, "-bit "); ok {
+		if n, err := strconv.Atoi(prefix); err == nil {
+			bits = n
+			value = rest
+		}
+	}
+
+	// Codecs such as FLAC report a bit count that is not a stored sample size
+	encoding, ok := probeEncodings[value]
+	if !ok {
+		return 0, ""
+	}
+
+	return bits, encoding
+}
+
+// parseClock parses a sox time such as "01:02:03.45", returning 0 when malformed
+func parseClock(clock string) time.Duration {
+	var d time.Duration
+
+	for _, part := range strings.Split(clock, ":") {
+		value, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return 0
+		}
+		d = d*60 + time.Duration(value*float64(time.Second))
+	}
+
+	return d
+}
+
+// samplesDuration converts a per-channel sample count to a duration without overflow
+func samplesDuration(samples int64, rate int) time.Duration {
+	seconds := samples / int64(rate)
+	rest := samples % int64(rate)
+
+	return time.Duration(seconds)*time.Second + time.Duration(rest*int64(time.Second)/int64(rate))
+}
+
+// spoolInput copies a reader to a temporary file, with the extension of the sniffed
+// container so sox can identify headerless variants, and returns its path
+func spoolInput(r io.Reader) (string, error) {
+	format, r, err := SniffFormat(r)
+	if err != nil {
+		return "", err
+	}
+
+	pattern := "sox-input-*"
+	if format.Type != "" {
+		pattern += format.Extension()
+	}
+
+	file, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary file: %w", err)
+	}
+
+	if _, err := io.Copy(file, r); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return "", fmt.Errorf("failed to spool input: %w", err)
+	}
+
+	if err := file.Close(); err != nil {
+		os.Remove(file.Name())
+		return "", fmt.Errorf("failed to spool input: %w", err)
+	}
+
+	return file.Name(), nil
+}
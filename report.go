@@ -0,0 +1,77 @@
+package sox
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ErrClipped is returned when Options.FailOnClip is set and sox clipped samples
+var ErrClipped = errors.New("sox clipped samples")
+
+// ConversionReport describes what sox reported on stderr during a successful run
+type ConversionReport struct {
+	ClippedSamples int64    // Samples clipped anywhere in the chain
+	Clips          []Clip   // Clipping per stage, in the order sox reported it
+	DitherApplied  bool     // sox dithered the output; only known with Options.VerbosityLevel 3 or more
+	Warnings       []string // sox warnings, without the "sox WARN" prefix, e.g. "wav: Premature EOF on .wav input file"
+}
+
+// Clip is clipping reported by sox for one stage of the chain
+type Clip struct {
+	Stage   string // Effect name (e.g. "rate", "gain", "dither"), "input" or "output"
+	Samples int64  // Number of clipped samples
+}
+
+// soxClipPattern matches clipping warnings such as "sox WARN rate: rate clipped 53
+// samples; decrease volume?" or "sox WARN sox: `out.wav' output clipped 1234 samples"
+var soxClipPattern = regexp.MustCompile("^\\S+ WARN \\S+: (?:`[^']*' )?(\\S+) clipped (\\d+) samples")
+
+// parseReport parses the stderr of a successful sox run
+func parseReport(stderr []byte) *ConversionReport {
+	report := &ConversionReport{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(stderr))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if m := soxClipPattern.FindStringSubmatch(line); m != nil {
+			samples, _ := strconv.ParseInt(m[2], 10, 64)
+			report.Clips = append(report.Clips, Clip{Stage: m[1], Samples: samples})
+			report.ClippedSamples += samples
+		}
+
+		if _, warning, ok := strings.Cut(line, " WARN "); ok {
+			report.Warnings = append(report.Warnings, warning)
+		}
+
+		// At -V3 sox lists the effects chain, including the dither it adds
+		if _, info, ok := strings.Cut(line, " INFO "); ok {
+			if strings.HasPrefix(info, "dither:") || strings.Contains(info, "effects chain: dither") {
+				report.DitherApplied = true
+			}
+		}
+	}
+
+	return report
+}
+
+// finishRun reports the stderr of a successful sox run to the report handler, and
+// returns an error matching ErrClipped when clipping is not allowed
+func (c *Task) finishRun(stderr []byte) error {
+	report := parseReport(stderr)
+
+	if c.reportHandler != nil {
+		c.reportHandler(report)
+	}
+
+	if c.Options.FailOnClip && report.ClippedSamples > 0 {
+		return fmt.Errorf("%w: %d samples", ErrClipped, report.ClippedSamples)
+	}
+
+	return nil
+}
@@ -0,0 +1,220 @@
+package sox
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Result describes a conversion for capacity planning and billing
+type Result struct {
+	Attempts       []Attempt         // sox runs, in order; the last one produced the output on success
+	Duration       time.Duration     // Wall time of the whole conversion, including retry backoff
+	Args           []string          // argv of the last run, starting with the sox path
+	ExitCode       int               // Exit code of the last run, -1 if sox was not started or was killed
+	BytesIn        int64             // Bytes sox read in the last run
+	BytesOut       int64             // Bytes sox wrote in the last run
+	InputDuration  time.Duration     // Playing time of the input, 0 when unknown
+	OutputDuration time.Duration     // Playing time of the output, 0 when unknown
+	UserTime       time.Duration     // CPU time sox spent in user mode, summed over the attempts
+	SystemTime     time.Duration     // CPU time sox spent in the kernel, summed over the attempts
+	MaxRSS         int64             // Peak resident set size of sox in bytes over the attempts, 0 when unsupported
+	Report         *ConversionReport // What sox reported on stderr, nil when the last run failed
+}
+
+// Attempt describes one sox run of a conversion
+type Attempt struct {
+	Duration   time.Duration // Wall time from starting sox until it exited
+	ExitCode   int           // -1 if sox was not started or was killed
+	BytesIn    int64         // Bytes sox read from stdin; 0 in path mode
+	BytesOut   int64         // Bytes sox wrote to stdout; 0 in path mode
+	UserTime   time.Duration // CPU time in user mode
+	SystemTime time.Duration // CPU time in the kernel
+	MaxRSS     int64         // Peak resident set size in bytes, 0 when unsupported
+	Err        error         // Why the run failed, nil on success
+}
+
+// ConvertWithResult converts like ConvertWithContext and describes the
+// conversion: its sox runs with their durations, exit codes and resource usage,
+// the argv, the bytes read and written and the playing time of the input and
+// output. Options.Timeout applies to each sox run as in ConvertWithContext,
+// including the probes for the playing times, which also stop with ctx.
+//
+// The result is returned even when the conversion fails, with the attempts made.
+// Byte counts of path-to-path conversions are the file sizes. Playing times are
+// computed from the byte counts for raw formats and probed for file paths;
+// they are 0 for other streams and when ctx is done before probing. CPU times come from the operating system,
+// and MaxRSS is reported on Linux, macOS and the BSDs.
+//
+// Example:
+//
+//	result, err := task.ConvertWithResult(ctx, "call.wav", "call.mp3")
+//	if err != nil {
+//		return err
+//	}
+//	log.Printf("%d attempts, %s of audio, cpu %s, rss %d KiB",
+//		len(result.Attempts), result.InputDuration, result.UserTime+result.SystemTime, result.MaxRSS/1024)
+func (c *Task) ConvertWithResult(ctx context.Context, input, output interface{}) (*Result, error) {
+	trace := &runTrace{}
+	start := time.Now()
+
+	err := c.ConvertWithContext(withRunTrace(ctx, trace), input, output)
+
+	result := trace.result()
+	result.Duration = time.Since(start)
+
+	if err != nil {
+		return result, err
+	}
+
+	inputPath, inputIsPath := input.(string)
+	outputPath, outputIsPath := output.(string)
+
+	if inputIsPath && outputIsPath {
+		result.BytesIn = fileSize(inputPath)
+		result.BytesOut = fileSize(outputPath)
+	}
+
+	result.InputDuration = c.playingTime(ctx, c.inputFormat(), inputPath, result.BytesIn)
+	result.OutputDuration = c.playingTime(ctx, c.outputFormat(), outputPath, result.BytesOut)
+
+	return result, nil
+}
+
+// playingTime returns the duration of n bytes of a raw format, or probes a path;
+// 0 when neither is possible or ctx is done
+func (c *Task) playingTime(ctx context.Context, format AudioFormat, path string, n int64) time.Duration {
+	if strings.EqualFold(format.Type, TYPE_RAW) {
+		if d := format.Duration(int(n)); d > 0 {
+			return d
+		}
+	}
+
+	if path == "" || ctx.Err() != nil {
+		return 0
+	}
+
+	info, err := c.Probe(ctx, path)
+	if err != nil {
+		return 0
+	}
+
+	return info.Duration
+}
+
+// fileSize returns the size of a file, or 0 when it cannot be read
+func fileSize(path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+
+	return info.Size()
+}
+
+// runTraceKey is the context key of the runTrace recording conversion runs
+type runTraceKey struct{}
+
+// withRunTrace returns a context whose sox runs are recorded in trace
+func withRunTrace(ctx context.Context, trace *runTrace) context.Context {
+	return context.WithValue(ctx, runTraceKey{}, trace)
+}
+
+// runTraceFrom returns the runTrace of a context, or nil
+func runTraceFrom(ctx context.Context) *runTrace {
+	trace, _ := ctx.Value(runTraceKey{}).(*runTrace)
+	return trace
+}
+
+// runTrace records the sox runs of a conversion
+type runTrace struct {
+	mu       sync.Mutex
+	attempts []Attempt
+	args     []string
+	stderr   []byte
+}
+
+// record adds a finished sox run
+func (t *runTrace) record(cmd *exec.Cmd, duration time.Duration, in *countingReader, out *countingWriter, stderr []byte, err error) {
+	attempt := Attempt{Duration: duration, ExitCode: -1, Err: err}
+
+	if in != nil {
+		attempt.BytesIn = in.n
+	}
+	if out != nil {
+		attempt.BytesOut = out.n
+	}
+
+	if state := cmd.ProcessState; state != nil {
+		attempt.ExitCode = state.ExitCode()
+		attempt.UserTime = state.UserTime()
+		attempt.SystemTime = state.SystemTime()
+		attempt.MaxRSS = maxRSS(state)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.attempts = append(t.attempts, attempt)
+	t.args = append([]string(nil), cmd.Args...)
+	t.stderr = append([]byte(nil), stderr...)
+}
+
+// result summarizes the recorded runs
+func (t *runTrace) result() *Result {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	result := &Result{
+		Attempts: append([]Attempt{}, t.attempts...),
+		Args:     t.args,
+		ExitCode: -1,
+	}
+
+	for _, attempt := range t.attempts {
+		result.UserTime += attempt.UserTime
+		result.SystemTime += attempt.SystemTime
+		result.MaxRSS = max(result.MaxRSS, attempt.MaxRSS)
+	}
+
+	if n := len(t.attempts); n > 0 {
+		last := t.attempts[n-1]
+		result.ExitCode = last.ExitCode
+		result.BytesIn = last.BytesIn
+		result.BytesOut = last.BytesOut
+
+		if last.Err == nil {
+			result.Report = parseReport(t.stderr)
+		}
+	}
+
+	return result
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	return n, err
+}
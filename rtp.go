@@ -0,0 +1,261 @@
+package sox
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrUnsupportedCodec is returned when an RTP codec cannot be converted by sox
+var ErrUnsupportedCodec = errors.New("codec not supported by sox")
+
+// rtpCodec describes an RTP payload format (RFC 3551)
+type rtpCodec struct {
+	name     string
+	clock    int
+	channels int
+}
+
+// rtpStaticPayloadTypes lists the static audio payload types of RFC 3551
+var rtpStaticPayloadTypes = map[int]rtpCodec{
+	0:  {name: "PCMU", clock: 8000, channels: 1},
+	3:  {name: "GSM", clock: 8000, channels: 1},
+	4:  {name: "G723", clock: 8000, channels: 1},
+	5:  {name: "DVI4", clock: 8000, channels: 1},
+	6:  {name: "DVI4", clock: 16000, channels: 1},
+	7:  {name: "LPC", clock: 8000, channels: 1},
+	8:  {name: "PCMA", clock: 8000, channels: 1},
+	9:  {name: "G722", clock: 8000, channels: 1},
+	10: {name: "L16", clock: 44100, channels: 2},
+	11: {name: "L16", clock: 44100, channels: 1},
+	12: {name: "QCELP", clock: 8000, channels: 1},
+	13: {name: "CN", clock: 8000, channels: 1},
+	14: {name: "MPA", clock: 90000},
+	15: {name: "G728", clock: 8000, channels: 1},
+	16: {name: "DVI4", clock: 11025, channels: 1},
+	17: {name: "DVI4", clock: 22050, channels: 1},
+	18: {name: "G729", clock: 8000, channels: 1},
+}
+
+// rtpUnsupportedCodecs explains why sox cannot convert some RTP codecs
+var rtpUnsupportedCodecs = map[string]string{
+	"G723":            "sox has no G.723.1 codec",
+	"G722":            "sox has no G.722 codec",
+	"G728":            "sox has no G.728 codec",
+	"G729":            "sox has no G.729 codec",
+	"G726-16":         "sox cannot read G.726 RTP payloads",
+	"G726-24":         "sox cannot read G.726 RTP payloads",
+	"G726-32":         "sox cannot read G.726 RTP payloads",
+	"G726-40":         "sox cannot read G.726 RTP payloads",
+	"DVI4":            "DVI4 payloads carry a per-packet ADPCM state header sox cannot parse",
+	"VDVI":            "sox has no VDVI codec",
+	"LPC":             "sox has no LPC codec",
+	"QCELP":           "sox has no QCELP codec",
+	"CN":              "comfort noise carries no audio samples",
+	"TELEPHONE-EVENT": "DTMF events carry no audio samples",
+	"OPUS":            "sox cannot read Opus RTP payloads",
+	"SPEEX":           "sox has no Speex codec",
+	"ILBC":            "sox has no iLBC codec",
+	"AMR":             "sox cannot read AMR RTP payload framing",
+	"AMR-WB":          "sox cannot read AMR-WB RTP payload framing",
+}
+
+// FormatFromRTPMap returns the AudioFormat of the RTP payload described by an SDP
+// rtpmap attribute. The "a=rtpmap:" prefix and the payload type are optional.
+// MPA payloads must be stripped of their RFC 2250 header before conversion.
+//
+// Example:
+//
+//	f, err := sox.FormatFromRTPMap("a=rtpmap:96 L16/16000/2")
+//	// f: raw, signed-integer 16-bit big-endian, 16000 Hz, 2 channels
+//
+//	_, err = sox.FormatFromRTPMap("a=rtpmap:18 G729/8000")
+//	// errors.Is(err, sox.ErrUnsupportedCodec) == true
+func FormatFromRTPMap(rtpmap string) (AudioFormat, error) {
+	value := strings.TrimSpace(rtpmap)
+	value = strings.TrimPrefix(value, "a=")
+	value = strings.TrimPrefix(value, "rtpmap:")
+
+	// Drop the payload type if present
+	if fields := strings.Fields(value); len(fields) == 2 {
+		value = fields[1]
+	} else if len(fields) != 1 {
+		return AudioFormat{}, fmt.Errorf("invalid rtpmap %q", rtpmap)
+	}
+
+	parts := strings.Split(value, "/")
+	if len(parts) < 2 || len(parts) > 3 {
+		return AudioFormat{}, fmt.Errorf("invalid rtpmap %q: expected NAME/CLOCK[/CHANNELS]", rtpmap)
+	}
+
+	codec := rtpCodec{name: strings.ToUpper(parts[0])}
+
+	clock, err := strconv.Atoi(parts[1])
+	if err != nil || clock <= 0 {
+		return AudioFormat{}, fmt.Errorf("invalid rtpmap %q: bad clock rate", rtpmap)
+	}
+	codec.clock = clock
+
+	codec.channels = 1
+	if len(parts) == 3 {
+		channels, err := strconv.Atoi(parts[2])
+		if err != nil || channels <= 0 {
+			return AudioFormat{}, fmt.Errorf("invalid rtpmap %q: bad channel count", rtpmap)
+		}
+		codec.channels = channels
+	}
+
+	return codec.format()
+}
+
+// FormatFromPayloadType returns the AudioFormat of a static RTP payload type (RFC 3551).
+// Dynamic payload types (96-127) are only defined by an rtpmap; use FormatFromRTPMap.
+//
+// Example:
+//
+//	f, err := sox.FormatFromPayloadType(8) // PCMA/8000: raw a-law, 8000 Hz, mono
+func FormatFromPayloadType(pt int) (AudioFormat, error) {
+	if pt >= 96 && pt <= 127 {
+		return AudioFormat{}, fmt.Errorf("dynamic payload type %d requires an rtpmap", pt)
+	}
+
+	codec, ok := rtpStaticPayloadTypes[pt]
+	if !ok {
+		return AudioFormat{}, fmt.Errorf("payload type %d is not a static audio payload type", pt)
+	}
+
+	return codec.format()
+}
+
+// format converts the codec to the AudioFormat of its payload
+func (r rtpCodec) format() (AudioFormat, error) {
+	if reason, ok := rtpUnsupportedCodecs[r.name]; ok {
+		return AudioFormat{}, fmt.Errorf("%w: %s: %s", ErrUnsupportedCodec, r.name, reason)
+	}
+
+	f := AudioFormat{
+		Type:       TYPE_RAW,
+		SampleRate: r.clock,
+		Channels:   r.channels,
+	}
+
+	switch r.name {
+	case "PCMU":
+		f.Encoding = MU_LAW
+		f.BitDepth = 8
+	case "PCMA":
+		f.Encoding = A_LAW
+		f.BitDepth = 8
+	case "L8":
+		f.Encoding = UNSIGNED_INTEGER
+		f.BitDepth = 8
+	case "L16":
+		f.Encoding = SIGNED_INTEGER
+		f.BitDepth = 16
+		f.Endian = "big"
+	case "L24":
+		f.Encoding = SIGNED_INTEGER
+		f.BitDepth = 24
+		f.Endian = "big"
+	case "GSM":
+		f.Type = TYPE_GSM
+	case "MPA":
+		// The 90 kHz RTP clock says nothing about the audio: sox reads it from the frames
+		return AudioFormat{Type: TYPE_MP3}, nil
+	default:
+		return AudioFormat{}, fmt.Errorf("%w: %s: unknown RTP codec", ErrUnsupportedCodec, r.name)
+	}
+
+	return f, nil
+}
+
+// rtpCodec returns the RTP codec carrying audio in this format
+func (f *AudioFormat) rtpCodec() (rtpCodec, error) {
+	channels := f.Channels
+	if channels == 0 {
+		channels = 1
+	}
+
+	codec := rtpCodec{clock: f.SampleRate, channels: channels}
+	typ := strings.ToLower(f.Type)
+	encoding := f.Encoding.Canonical()
+
+	switch {
+	case typ == TYPE_MP3:
+		return rtpCodec{name: "MPA", clock: 90000}, nil
+	case typ == TYPE_GSM:
+		codec.name = "GSM"
+	case typ != TYPE_RAW:
+		return codec, fmt.Errorf("%w: %s files have no RTP payload format", ErrUnsupportedCodec, f.Type)
+	case encoding == MU_LAW:
+		codec.name = "PCMU"
+	case encoding == A_LAW:
+		codec.name = "PCMA"
+	case encoding == UNSIGNED_INTEGER && f.BitDepth == 8:
+		codec.name = "L8"
+	case encoding == SIGNED_INTEGER && (f.BitDepth == 16 || f.BitDepth == 24):
+		if f.Endian != "big" {
+			return codec, fmt.Errorf("L%d RTP payloads require big-endian samples", f.BitDepth)
+		}
+		codec.name = fmt.Sprintf("L%d", f.BitDepth)
+	default:
+		return codec, fmt.Errorf("%w: no RTP payload format for %s", ErrUnsupportedCodec, f.String())
+	}
+
+	if codec.clock <= 0 {
+		return codec, fmt.Errorf("%s RTP payloads require a sample rate", codec.name)
+	}
+
+	return codec, nil
+}
+
+// PayloadType returns the static RTP payload type (RFC 3551) matching the format, if any.
+//
+// Example:
+//
+//	pt, ok := sox.ULAW_8K_MONO.PayloadType() // 0, true
+func (f *AudioFormat) PayloadType() (int, bool) {
+	codec, err := f.rtpCodec()
+	if err != nil {
+		return 0, false
+	}
+
+	for pt, static := range rtpStaticPayloadTypes {
+		if static == codec {
+			return pt, true
+		}
+	}
+
+	return 0, false
+}
+
+// RTPMap returns the SDP rtpmap attribute for the format, e.g. "a=rtpmap:0 PCMU/8000".
+// Formats matching a static payload type use it; other formats use dynamicPT.
+//
+// Example:
+//
+//	l16 := sox.AudioFormat{Type: sox.TYPE_RAW, Encoding: sox.SIGNED_INTEGER, BitDepth: 16,
+//		Endian: "big", SampleRate: 16000, Channels: 1}
+//	line, err := l16.RTPMap(96) // "a=rtpmap:96 L16/16000"
+func (f *AudioFormat) RTPMap(dynamicPT int) (string, error) {
+	codec, err := f.rtpCodec()
+	if err != nil {
+		return "", err
+	}
+
+	pt, ok := f.PayloadType()
+	if !ok {
+		if dynamicPT < 96 || dynamicPT > 127 {
+			return "", fmt.Errorf("dynamic payload type must be between 96 and 127, got %d", dynamicPT)
+		}
+		pt = dynamicPT
+	}
+
+	line := fmt.Sprintf("a=rtpmap:%d %s/%d", pt, codec.name, codec.clock)
+	if codec.channels > 1 {
+		line += fmt.Sprintf("/%d", codec.channels)
+	}
+
+	return line, nil
+}
@@ -0,0 +1,10 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package sox
+
+import "os"
+
+// maxRSS returns 0; the peak resident set size is not reported on this platform
+func maxRSS(state *os.ProcessState) int64 {
+	return 0
+}
@@ -0,0 +1,24 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package sox
+
+import (
+	"os"
+	"runtime"
+	"syscall"
+)
+
+// maxRSS returns the peak resident set size of an exited process in bytes
+func maxRSS(state *os.ProcessState) int64 {
+	usage, ok := state.SysUsage().(*syscall.Rusage)
+	if !ok {
+		return 0
+	}
+
+	// macOS reports bytes, the others kilobytes
+	if runtime.GOOS == "darwin" {
+		return int64(usage.Maxrss)
+	}
+
+	return int64(usage.Maxrss) * 1024
+}
@@ -0,0 +1,252 @@
+package sox
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+// SilenceConfig configures silence and speech detection
+type SilenceConfig struct {
+	Threshold  float64       // Level in dBFS at or below which a window is silence, e.g. -40
+	MinSilence time.Duration // Shorter pauses between speech are part of the speech
+	MinSpeech  time.Duration // Shorter sounds (clicks, breaths) are part of the silence
+	Window     time.Duration // Length of the analysis window; 20ms when zero
+	PerChannel bool          // Detect segments in each channel rather than in the mixdown
+}
+
+// DefaultSilenceConfig returns a configuration suited to speech recordings
+func DefaultSilenceConfig() SilenceConfig {
+	return SilenceConfig{
+		Threshold:  -40,
+		MinSilence: 300 * time.Millisecond,
+		MinSpeech:  100 * time.Millisecond,
+		Window:     20 * time.Millisecond,
+	}
+}
+
+// Validate checks the configuration
+func (s SilenceConfig) Validate() error {
+	switch {
+	case s.Threshold >= 0 || math.IsNaN(s.Threshold):
+		return fmt.Errorf("invalid silence config: threshold must be below 0 dBFS, got %v", s.Threshold)
+	case s.MinSilence < 0 || s.MinSpeech < 0 || s.Window < 0:
+		return fmt.Errorf("invalid silence config: durations must not be negative")
+	}
+
+	return nil
+}
+
+// Segment is a stretch of speech or silence
+type Segment struct {
+	Start   time.Duration // Offset from the start of the audio
+	End     time.Duration // Offset of the end, exclusive
+	Speech  bool          // Whether the segment is speech (sound above the threshold)
+	Channel int           // Channel number from 1 with SilenceConfig.PerChannel, 0 for the mixdown
+	Level   float64       // RMS level of the segment in dBFS, -Inf for digital silence
+}
+
+// Duration returns the length of the segment
+func (s Segment) Duration() time.Duration {
+	return s.End - s.Start
+}
+
+// DetectSegments splits a recording into speech and silence segments. See
+// Task.DetectSegments; DetectSegments uses a Task with the default options and
+// format as its Input.
+//
+// Example:
+//
+//	segments, err := sox.DetectSegments(ctx, "call.wav", sox.AudioFormat{}, sox.DefaultSilenceConfig())
+//	if err != nil {
+//		return err
+//	}
+//	for _, seg := range segments {
+//		if seg.Speech {
+//			log.Printf("speech %s - %s", seg.Start, seg.End)
+//		}
+//	}
+func DetectSegments(ctx context.Context, input interface{}, format AudioFormat, config SilenceConfig) ([]Segment, error) {
+	return New(format, format).DetectSegments(ctx, input, config)
+}
+
+// DetectSegments splits an audio file or stream in the Input format into
+// alternating speech and silence segments covering the whole recording. sox
+// decodes the input (a path or an io.Reader) to PCM at its sample rate, and the
+// RMS level of each window is compared with the threshold.
+// Pauses shorter than MinSilence are then merged into the surrounding speech, and
+// sounds shorter than MinSpeech into the surrounding silence.
+//
+// By default the channels are mixed down. With PerChannel, each channel is
+// analyzed on its own and its segments are returned in turn, ordered by channel.
+// The rate and channel count come from the Input format, or from probing a path;
+// readers without Input.SampleRate are decoded at 16 kHz.
+//
+// The decoding runs Options.SoxPath, honors Options.Timeout and goes through the
+// Task circuit breaker. Options.Effects are not applied.
+//
+// Example:
+//
+//	// Skip leading dead air before speech recognition
+//	task := sox.New(sox.PCM_RAW_8K_MONO, sox.PCM_RAW_8K_MONO)
+//	segments, err := task.DetectSegments(ctx, bytes.NewReader(pcm), sox.DefaultSilenceConfig())
+//	if err != nil {
+//		return err
+//	}
+//	if len(segments) > 1 && !segments[0].Speech {
+//		pcm = pcm[task.Input.BytesFor(segments[0].End):]
+//	}
+func (c *Task) DetectSegments(ctx context.Context, input interface{}, config SilenceConfig) ([]Segment, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	if config.Window == 0 {
+		config.Window = DefaultSilenceConfig().Window
+	}
+
+	rate, channels, err := c.inputLayout(ctx, "segment detection", input, config.PerChannel)
+	if err != nil {
+		return nil, err
+	}
+
+	detector := newSegmentDetector(channels, max(1, int(int64(rate)*int64(config.Window)/int64(time.Second))))
+
+	if err := c.decodePCM(ctx, "segment detection", input, rate, channels, detector.add); err != nil {
+		return nil, err
+	}
+	detector.finish()
+
+	segments := []Segment{}
+	for ch := 0; ch < channels; ch++ {
+		channel := 0
+		if config.PerChannel {
+			channel = ch + 1
+		}
+
+		for _, r := range detector.runs(ch, config, rate) {
+			segments = append(segments, Segment{
+				Start:   samplesDuration(r.start, rate),
+				End:     samplesDuration(r.end, rate),
+				Speech:  r.speech,
+				Channel: channel,
+				Level:   10 * math.Log10(r.energy/float64(r.end-r.start)),
+			})
+		}
+	}
+
+	return segments, nil
+}
+
+// segmentDetector accumulates the mean square of each analysis window per channel
+type segmentDetector struct {
+	channels     int
+	windowFrames int
+	sums         []float64   // Sum of squares of the current window, per channel
+	filled       int         // Frames in the current window
+	windows      [][]float64 // Mean square of each window, per channel
+	frames       int64       // Frames seen
+}
+
+// newSegmentDetector returns a detector with windows of windowFrames frames
+func newSegmentDetector(channels, windowFrames int) *segmentDetector {
+	return &segmentDetector{
+		channels:     channels,
+		windowFrames: windowFrames,
+		sums:         make([]float64, channels),
+		windows:      make([][]float64, channels),
+	}
+}
+
+// add accumulates interleaved samples of whole frames
+func (d *segmentDetector) add(samples []float32) {
+	for i := 0; i+d.channels <= len(samples); i += d.channels {
+		for ch := 0; ch < d.channels; ch++ {
+			v := float64(samples[i+ch])
+			d.sums[ch] += v * v
+		}
+
+		d.frames++
+		d.filled++
+		if d.filled == d.windowFrames {
+			d.closeWindow()
+		}
+	}
+}
+
+// finish closes the last, partial window
+func (d *segmentDetector) finish() {
+	if d.filled > 0 {
+		d.closeWindow()
+	}
+}
+
+// closeWindow records the mean square of the current window and starts a new one
+func (d *segmentDetector) closeWindow() {
+	for ch := 0; ch < d.channels; ch++ {
+		d.windows[ch] = append(d.windows[ch], d.sums[ch]/float64(d.filled))
+		d.sums[ch] = 0
+	}
+	d.filled = 0
+}
+
+// segmentRun is a run of windows of the same kind, in frames
+type segmentRun struct {
+	speech     bool
+	start, end int64
+	energy     float64 // Sum of squares over the run
+}
+
+// runs classifies the windows of a channel and applies the minimum durations
+func (d *segmentDetector) runs(ch int, config SilenceConfig, rate int) []segmentRun {
+	threshold := math.Pow(10, config.Threshold/10)
+
+	var runs []segmentRun
+	for i, meanSquare := range d.windows[ch] {
+		start := int64(i) * int64(d.windowFrames)
+		end := min(start+int64(d.windowFrames), d.frames)
+
+		runs = append(runs, segmentRun{
+			speech: meanSquare > threshold,
+			start:  start,
+			end:    end,
+			energy: meanSquare * float64(end-start),
+		})
+	}
+	runs = mergeRuns(runs)
+
+	// Bridge pauses within speech, then drop sounds too short to be speech
+	minSilence := int64(config.MinSilence) * int64(rate) / int64(time.Second)
+	for i := 1; i < len(runs)-1; i++ {
+		if !runs[i].speech && runs[i].end-runs[i].start < minSilence {
+			runs[i].speech = true
+		}
+	}
+	runs = mergeRuns(runs)
+
+	minSpeech := int64(config.MinSpeech) * int64(rate) / int64(time.Second)
+	for i := range runs {
+		if runs[i].speech && runs[i].end-runs[i].start < minSpeech {
+			runs[i].speech = false
+		}
+	}
+
+	return mergeRuns(runs)
+}
+
+// mergeRuns joins adjacent runs of the same kind
+func mergeRuns(runs []segmentRun) []segmentRun {
+	var merged []segmentRun
+
+	for _, r := range runs {
+		if n := len(merged); n > 0 && merged[n-1].speech == r.speech {
+			merged[n-1].end = r.end
+			merged[n-1].energy += r.energy
+			continue
+		}
+		merged = append(merged, r)
+	}
+
+	return merged
+}
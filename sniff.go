@@ -0,0 +1,294 @@
+package sox
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// sniffLen is the number of leading bytes inspected by SniffFormat, enough to
+// skip small chunks (JUNK, LIST) placed before the WAV fmt chunk
+const sniffLen = 4096
+
+// SniffFormat detects the audio container of r from its first bytes, without
+// consuming them. It returns the detected format (an empty Type when the content
+// is not recognized) and a reader that yields the whole input, peeked bytes
+// included. Seekable readers are rewound and returned as is.
+//
+// Recognized containers: RIFF/WAVE, fLaC, OggS (Vorbis or Opus), ID3 or MPEG
+// frame sync (mp3), FORM/AIFF and .snd (au). For WAV, FLAC and au the sample
+// rate, channels and bit depth are parsed from the header as well.
+//
+// Example:
+//
+//	format, input, err := sox.SniffFormat(upload)
+//	if err != nil {
+//		return err
+//	}
+//	if format.Type == "" {
+//		format = sox.PCM_RAW_8K_MONO
+//	}
+//	err = sox.New(format, sox.FLAC_16K_MONO_LE).Convert(input, output)
+func SniffFormat(r io.Reader) (AudioFormat, io.Reader, error) {
+	if rs, ok := r.(io.ReadSeeker); ok {
+		if start, err := rs.Seek(0, io.SeekCurrent); err == nil {
+			header := make([]byte, sniffLen)
+			n, err := io.ReadFull(rs, header)
+			if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+				return AudioFormat{}, rs, fmt.Errorf("failed to read input header: %w", err)
+			}
+
+			if _, err := rs.Seek(start, io.SeekStart); err != nil {
+				return AudioFormat{}, rs, fmt.Errorf("failed to rewind input: %w", err)
+			}
+
+			f, _ := DetectFormat(header[:n])
+			return f, rs, nil
+		}
+	}
+
+	br, ok := r.(*bufio.Reader)
+	if !ok || br.Size() < sniffLen {
+		br = bufio.NewReaderSize(r, sniffLen)
+	}
+
+	header, err := br.Peek(sniffLen)
+	if err != nil && err != io.EOF {
+		return AudioFormat{}, br, fmt.Errorf("failed to read input header: %w", err)
+	}
+
+	f, _ := DetectFormat(header)
+	return f, br, nil
+}
+
+// DetectFormat detects the audio container from the leading bytes of a file or
+// stream. It reports false when the content is not recognized, e.g. for raw audio.
+//
+// Example:
+//
+//	if format, ok := sox.DetectFormat(firstPacket); ok {
+//		log.Printf("input is %s", format)
+//	}
+func DetectFormat(header []byte) (AudioFormat, bool) {
+	switch {
+	case len(header) >= 12 && bytes.Equal(header[0:4], []byte("RIFF")) && bytes.Equal(header[8:12], []byte("WAVE")):
+		return sniffWAV(header), true
+	case bytes.HasPrefix(header, []byte("fLaC")):
+		return sniffFLAC(header), true
+	case bytes.HasPrefix(header, []byte("OggS")):
+		if bytes.Contains(header[:min(len(header), 64)], []byte("OpusHead")) {
+			return AudioFormat{Type: TYPE_OPUS}, true
+		}
+		return AudioFormat{Type: TYPE_OGG}, true
+	case bytes.HasPrefix(header, []byte("ID3")):
+		return AudioFormat{Type: TYPE_MP3}, true
+	case len(header) >= 12 && bytes.Equal(header[0:4], []byte("FORM")) &&
+		(bytes.Equal(header[8:12], []byte("AIFF")) || bytes.Equal(header[8:12], []byte("AIFC"))):
+		return AudioFormat{Type: TYPE_AIFF}, true
+	case bytes.HasPrefix(header, []byte(".snd")):
+		return sniffAU(header), true
+	}
+
+	if f, ok := sniffMPEG(header); ok {
+		return f, true
+	}
+
+	return AudioFormat{}, false
+}
+
+// wavEncodings maps WAVE format tags to sox encodings
+var wavEncodings = map[uint16]Encoding{
+	0x0003: FLOATING_POINT,
+	0x0006: A_LAW,
+	0x0007: MU_LAW,
+	0x0002: MS_ADPCM,
+	0x0011: IMA_ADPCM,
+	0x0031: GSM_FULL_RATE,
+}
+
+// sniffWAV parses the fmt chunk of a RIFF/WAVE header, if it is within the header bytes
+func sniffWAV(header []byte) AudioFormat {
+	f := AudioFormat{Type: TYPE_WAV}
+
+	for offset := 12; offset+8 <= len(header); {
+		id := string(header[offset : offset+4])
+		size := int(binary.LittleEndian.Uint32(header[offset+4 : offset+8]))
+		body := offset + 8
+
+		if id != "fmt " {
+			offset = body + size + size&1
+			continue
+		}
+
+		if size < 16 || body+16 > len(header) {
+			break
+		}
+
+		tag := binary.LittleEndian.Uint16(header[body:])
+		f.Channels = int(binary.LittleEndian.Uint16(header[body+2:]))
+		f.SampleRate = int(binary.LittleEndian.Uint32(header[body+4:]))
+		f.BitDepth = int(binary.LittleEndian.Uint16(header[body+14:]))
+
+		// WAVE_FORMAT_EXTENSIBLE keeps the real format tag in its sub-format GUID
+		if tag == 0xFFFE && size >= 40 && body+26 <= len(header) {
+			tag = binary.LittleEndian.Uint16(header[body+24:])
+		}
+
+		switch {
+		case tag == 0x0001 && f.BitDepth <= 8:
+			f.Encoding = UNSIGNED_INTEGER
+		case tag == 0x0001:
+			f.Encoding = SIGNED_INTEGER
+		default:
+			f.Encoding = wavEncodings[tag]
+		}
+
+		break
+	}
+
+	return f
+}
+
+// sniffFLAC parses the STREAMINFO block following the fLaC marker
+func sniffFLAC(header []byte) AudioFormat {
+	f := AudioFormat{Type: TYPE_FLAC}
+
+	// Marker (4), block header (4), block sizes (4), frame sizes (6), then
+	// 20 bits sample rate, 3 bits channels - 1, 5 bits bits per sample - 1
+	if len(header) < 22 || header[4]&0x7F != 0 {
+		return f
+	}
+
+	info := header[18:22]
+	f.SampleRate = int(info[0])<<12 | int(info[1])<<4 | int(info[2])>>4
+	f.Channels = int(info[2]>>1&0x07) + 1
+	f.BitDepth = int((info[2]&0x01)<<4|info[3]>>4) + 1
+
+	return f
+}
+
+// auEncodings maps .snd encoding codes to sox encodings and bit depths
+var auEncodings = map[uint32]struct {
+	encoding Encoding
+	bits     int
+}{
+	1:  {MU_LAW, 8},
+	2:  {SIGNED_INTEGER, 8},
+	3:  {SIGNED_INTEGER, 16},
+	4:  {SIGNED_INTEGER, 24},
+	5:  {SIGNED_INTEGER, 32},
+	6:  {FLOATING_POINT, 32},
+	7:  {FLOATING_POINT, 64},
+	27: {A_LAW, 8},
+}
+
+// sniffAU parses the big-endian header of a Sun/NeXT .snd file
+func sniffAU(header []byte) AudioFormat {
+	f := AudioFormat{Type: TYPE_AU}
+
+	if len(header) < 24 {
+		return f
+	}
+
+	if enc, ok := auEncodings[binary.BigEndian.Uint32(header[12:16])]; ok {
+		f.Encoding = enc.encoding
+		f.BitDepth = enc.bits
+	}
+	f.SampleRate = int(binary.BigEndian.Uint32(header[16:20]))
+	f.Channels = int(binary.BigEndian.Uint32(header[20:24]))
+
+	return f
+}
+
+// mpegBitrates holds bitrates in kbps by [MPEG-1][layer index 1..3][bitrate index]
+var mpegBitrates = [2][4][16]int{
+	// MPEG-2 and MPEG-2.5
+	{
+		{},
+		{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},      // Layer III
+		{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},      // Layer II
+		{0, 32, 48, 56, 64, 80, 96, 112, 128, 144, 160, 176, 192, 224, 256}, // Layer I
+	},
+	// MPEG-1
+	{
+		{},
+		{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320},     // Layer III
+		{0, 32, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384},    // Layer II
+		{0, 32, 64, 96, 128, 160, 192, 224, 256, 288, 320, 352, 384, 416, 448}, // Layer I
+	},
+}
+
+// mpegFrame describes an MPEG audio frame header
+type mpegFrame struct {
+	version    byte // 3 = MPEG-1, 2 = MPEG-2, 0 = MPEG-2.5
+	layer      byte // 3 = Layer I, 2 = Layer II, 1 = Layer III
+	sampleRate int
+	channels   int
+	length     int
+}
+
+// parseMPEGFrame parses a 4-byte MPEG audio frame header
+func parseMPEGFrame(h []byte) (mpegFrame, bool) {
+	if len(h) < 4 || h[0] != 0xFF || h[1]&0xE0 != 0xE0 {
+		return mpegFrame{}, false
+	}
+
+	frame := mpegFrame{version: h[1] >> 3 & 0x03, layer: h[1] >> 1 & 0x03}
+	bitrateIndex := h[2] >> 4
+	rateIndex := h[2] >> 2 & 0x03
+	padding := int(h[2] >> 1 & 0x01)
+
+	// Reserved version and layer (the latter is also the AAC ADTS sync), free and bad bitrates
+	if frame.version == 1 || frame.layer == 0 || bitrateIndex == 0 || bitrateIndex == 0x0F || rateIndex == 3 {
+		return mpegFrame{}, false
+	}
+
+	frame.sampleRate = []int{44100, 48000, 32000}[rateIndex]
+	switch frame.version {
+	case 2:
+		frame.sampleRate /= 2
+	case 0:
+		frame.sampleRate /= 4
+	}
+
+	frame.channels = 2
+	if h[3]>>6 == 3 {
+		frame.channels = 1
+	}
+
+	mpeg1 := 0
+	if frame.version == 3 {
+		mpeg1 = 1
+	}
+	bitrate := mpegBitrates[mpeg1][frame.layer][bitrateIndex] * 1000
+
+	switch {
+	case frame.layer == 3:
+		frame.length = (12*bitrate/frame.sampleRate + padding) * 4
+	case frame.layer == 1 && mpeg1 == 0:
+		frame.length = 72*bitrate/frame.sampleRate + padding
+	default:
+		frame.length = 144*bitrate/frame.sampleRate + padding
+	}
+
+	return frame, frame.length > 4
+}
+
+// sniffMPEG detects a bare MPEG audio stream. Frame sync is only 11 bits, so the
+// header of the following frame must match as well, to avoid taking raw audio
+// for mp3.
+func sniffMPEG(header []byte) (AudioFormat, bool) {
+	first, ok := parseMPEGFrame(header)
+	if !ok {
+		return AudioFormat{}, false
+	}
+
+	next, ok := parseMPEGFrame(header[min(first.length, len(header)):])
+	if !ok || next.version != first.version || next.layer != first.layer || next.sampleRate != first.sampleRate {
+		return AudioFormat{}, false
+	}
+
+	return AudioFormat{Type: TYPE_MP3, SampleRate: first.sampleRate, Channels: first.channels}, true
+}
@@ -14,10 +14,13 @@ package sox
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 )
@@ -49,12 +52,14 @@ type Task struct {
 	circuitBreaker *CircuitBreaker
 	retryConfig    RetryConfig
 
+	// Lifecycle state
+	state     TaskState
+	stateLock sync.Mutex
+
 	// Streaming state
 	streamMode       bool
 	streamBuffer     *bytes.Buffer
 	streamLock       sync.Mutex
-	streamStarted    bool
-	streamClosed     bool
 	streamCmd        *exec.Cmd
 	streamStdin      io.WriteCloser
 	streamStdout     io.ReadCloser
@@ -62,18 +67,53 @@ type Task struct {
 	streamOutputDone chan error
 
 	// Ticker state
-	tickerMode     bool
-	ticker         *time.Ticker
-	tickerDuration time.Duration
-	tickerStop     chan struct{}
-	tickerBuffer   *bytes.Buffer
-	tickerLock     sync.Mutex
+	tickerMode        bool
+	ticker            *time.Ticker
+	tickerDuration    time.Duration
+	tickerStop        chan struct{}
+	tickerBuffer      *bytes.Buffer // audio written since the last flush
+	tickerRecording   []byte        // whole recording, kept for cumulative file output
+	tickerLock        sync.Mutex
+	tickerOverlap     time.Duration
+	tickerHandler     func(Chunk)
+	tickerSeq         int    // sequence number of the last converted chunk
+	tickerFlushed     int    // bytes delivered in converted chunks so far
+	tickerTaken       int    // bytes of the recording detached for chunks
+	tickerWritten     int    // bytes of the recording in the output file
+	tickerCarry       []byte // audio of a cancelled or failed flush, for the next flush
+	tickerTail        []byte // overlap carried into the next chunk
+	tickerRun         int    // incremented by resetTickerState, so flushes of an earlier run keep off the state
+	tickerMaxInFlight int
+	tickerFree        chan *bytes.Buffer
+	tickerQueue       chan *tickerFlush
+	tickerSlots       chan struct{}
+	tickerDone        chan struct{}
+	tickerErrs        []error
+	outputTemplate    string
+	manifestPath      string
 
 	outputPath string
 
 	// Path mode (direct file handling, no piping)
 	pathMode  bool
 	inputPath string
+
+	// Input type sniffed from the content when Input.Type is empty
+	sniffedType string
+
+	// Output type inferred from the output path when Output.Type is empty
+	outputType string
+
+	// Output encoder settings, see WithEncoder
+	encoder EncoderSettings
+
+	// Frame-aligned writes, see WithFrameAlignment
+	frameAligned bool
+	writeCarry   []byte // partial frame held back by the last Write
+
+	// Stderr reports of successful runs, see WithReportHandler
+	reportHandler func(*ConversionReport)
+	streamStderr  *bytes.Buffer
 }
 
 // New creates a new Task with input and output formats.
@@ -119,16 +159,20 @@ func New(args ...interface{}) *Task {
 		retryConfig:    DefaultRetryConfig(),
 		streamBuffer:   &bytes.Buffer{},
 		tickerBuffer:   &bytes.Buffer{},
-		tickerStop:     make(chan struct{}),
+
+		tickerMaxInFlight: defaultMaxInFlightFlushes,
+		tickerFree:        make(chan *bytes.Buffer, defaultMaxInFlightFlushes),
 	}
 }
 
 // Convert performs a one-time audio conversion without needing to instantiate sox.New.
 // It automatically detects the input format:
-//   - wav, flac, and mp3: auto-detected by sox (no -t flag needed)
+//   - File paths: by extension (wav, flac, mp3, ogg, ...)
+//   - io.Reader: by content, see SniffFormat
 //   - Other formats: defaults to raw type (-t raw)
 //
-// The output format is specified via the options parameter.
+// The output format is specified via the options parameter. When options.Type is
+// empty and output is a file path, the type is inferred from its extension.
 //
 // Example:
 //
@@ -150,8 +194,22 @@ func New(args ...interface{}) *Task {
 //		Type: "flac",
 //	})
 func Convert(input interface{}, output interface{}, options Options) error {
+	inputFormat := toFormatType(input)
+
+	// Readers carry no file extension: detect the container from the content
+	if reader, ok := input.(io.Reader); ok {
+		sniffed, replay, err := SniffFormat(reader)
+		if err != nil {
+			return err
+		}
+		if sniffed.Type != "" {
+			inputFormat.Type = sniffed.Type
+		}
+		input = replay
+	}
+
 	// Create task with detected input format and provided output format
-	task := New(toFormatType(input), &options)
+	task := New(inputFormat, &options)
 
 	// Perform conversion
 	return task.Convert(input, output)
@@ -207,13 +265,48 @@ func NewStream(input AudioFormat, output AudioFormat) *Task {
 //
 //	opts := DefaultOptions()
 //	opts.Timeout = 30 * time.Second
-//	opts.CompressionLevel = 8
+//	opts.Effects = []string{"norm"}
 //	task := New(input, output).WithOptions(opts)
 func (c *Task) WithOptions(opts ConversionOptions) *Task {
 	c.Options = opts
 	return c
 }
 
+// WithEncoder sets typed encoder settings for the output, such as the FLAC
+// compression level or the MP3 bitrate. They replace the deprecated
+// Options.CompressionLevel and Options.Quality, and are validated against the
+// output type before sox runs.
+//
+// Example:
+//
+//	task := New(PCM_RAW_8K_MONO, FLAC_16K_MONO_LE).
+//		WithEncoder(FLACSettings{Level: 8})
+//
+//	task := New(WAV_16K_MONO, AudioFormat{Type: TYPE_MP3}).
+//		WithEncoder(MP3Settings{Bitrate: 128})
+func (c *Task) WithEncoder(settings EncoderSettings) *Task {
+	c.encoder = settings
+	return c
+}
+
+// WithReportHandler sets a function receiving the report of each successful sox
+// conversion run (each attempt, ticker flush or stream), parsed from stderr: clipped
+// samples per stage, dither and other warnings. Ticker flushes may call it
+// concurrently. Warnings need the default verbosity or more; dither is only
+// reported with Options.VerbosityLevel 3 or more.
+//
+// Example:
+//
+//	task := New(WAV_16K_MONO, ULAW_8K_MONO).WithReportHandler(func(r *ConversionReport) {
+//		if r.ClippedSamples > 0 {
+//			log.Printf("clipped %d samples: %v", r.ClippedSamples, r.Clips)
+//		}
+//	})
+func (c *Task) WithReportHandler(fn func(*ConversionReport)) *Task {
+	c.reportHandler = fn
+	return c
+}
+
 // WithCircuitBreaker sets a custom circuit breaker for the Task.
 // By default, a circuit breaker is created with sensible defaults.
 // Override this for custom failure thresholds and reset timeouts.
@@ -301,8 +394,29 @@ func (c *Task) WithTicker(interval time.Duration) *Task {
 	return c
 }
 
+// WithFrameAlignment makes Write in stream and ticker mode pass on whole Input
+// frames only. A trailing partial frame is held back and prepended to the next
+// Write, so a sample is never split between sox writes or ticker flushes. A partial
+// frame still held back when the Task stops is dropped. Input must define BitDepth
+// and Channels.
+//
+// Example:
+//
+//	task := New(PCM_RAW_16K_MONO, FLAC_16K_MONO).
+//		WithTicker(3*time.Second).
+//		WithFrameAlignment()
+//	task.Start()
+//
+//	task.Write([]byte{0x01, 0x02, 0x03}) // writes one sample, holds back 0x03
+//	task.Write([]byte{0x04})             // writes 0x03 0x04
+func (c *Task) WithFrameAlignment() *Task {
+	c.frameAligned = true
+	return c
+}
+
 // WithOutputPath sets the output file path for conversions.
-// Used with ticker mode or stream mode to write directly to a file.
+// Used with ticker mode or stream mode to write directly to a file. When
+// Output.Type is empty, Start takes it from the path extension.
 //
 // Example:
 //
@@ -390,6 +504,14 @@ func (c *Task) ConvertWithContext(ctx context.Context, args ...interface{}) erro
 	input := args[0]
 	output := args[1]
 
+	// Infer or check the output type from the output path extension
+	c.outputType = ""
+	if outputPath, ok := output.(string); ok {
+		if err := c.inferOutputType(outputPath); err != nil {
+			return err
+		}
+	}
+
 	// Check if this is path-based conversion (optimize by avoiding piping)
 	if inputPath, ok := input.(string); ok {
 		if outputPath, ok := output.(string); ok {
@@ -434,6 +556,17 @@ func (c *Task) ConvertWithContext(ctx context.Context, args ...interface{}) erro
 		return fmt.Errorf("output must be io.Writer or string (file path), got %T", output)
 	}
 
+	// Sniff the container when no input type is configured
+	c.sniffedType = ""
+	if c.Input.Type == "" {
+		sniffed, reader, err := SniffFormat(inputReader)
+		if err != nil {
+			return err
+		}
+		inputReader = reader
+		c.sniffedType = sniffed.Type
+	}
+
 	// Convert input to ReadSeeker for retry support
 	var seekableInput io.ReadSeeker
 	if seeker, ok := inputReader.(io.ReadSeeker); ok {
@@ -471,19 +604,31 @@ func (c *Task) Write(data []byte) (int, error) {
 		c.tickerLock.Lock()
 		defer c.tickerLock.Unlock()
 
-		return c.tickerBuffer.Write(data)
+		// Checked under the lock: Stop leaves Running before its final flush
+		// takes the lock, so a Write is either in that flush or rejected
+		if err := c.requireState("write", TaskRunning); err != nil {
+			return 0, err
+		}
+
+		frames := c.alignWrite(data)
+
+		if c.cumulativeOutput() {
+			c.tickerRecording = append(c.tickerRecording, frames...)
+		}
+
+		if !c.recordingOnly() {
+			c.tickerBuffer.Write(frames)
+		}
+
+		return len(data), nil
 	}
 
 	if !c.streamMode {
 		return 0, fmt.Errorf("write only available in stream or ticker mode")
 	}
 
-	if !c.streamStarted {
-		return 0, fmt.Errorf("stream not started, call Start() first")
-	}
-
-	if c.streamClosed {
-		return 0, fmt.Errorf("stream is closed")
+	if err := c.requireState("write", TaskRunning); err != nil {
+		return 0, err
 	}
 
 	if c.streamStdin == nil {
@@ -492,9 +637,37 @@ func (c *Task) Write(data []byte) (int, error) {
 
 	c.streamLock.Lock()
 	defer c.streamLock.Unlock()
-	c.streamBuffer.Write(data)
 
-	return c.streamStdin.Write(data)
+	carried := len(c.writeCarry)
+	frames := c.alignWrite(data)
+	c.streamBuffer.Write(frames)
+
+	n, err := c.streamStdin.Write(frames)
+	if err != nil {
+		// Bytes carried over from the previous Write were already counted
+		return max(n-carried, 0), err
+	}
+
+	return len(data), nil
+}
+
+// alignWrite prepends the partial frame held back by the previous Write and holds
+// back the trailing partial frame of data, returning the bytes to pass on. It returns
+// data unchanged unless frame alignment is enabled (assumes the write lock is held).
+func (c *Task) alignWrite(data []byte) []byte {
+	frame := c.Input.FrameSize()
+	if !c.frameAligned || frame == 0 {
+		return data
+	}
+
+	if len(c.writeCarry) > 0 {
+		data = append(append(make([]byte, 0, len(c.writeCarry)+len(data)), c.writeCarry...), data...)
+	}
+
+	whole := len(data) - len(data)%frame
+	c.writeCarry = append(c.writeCarry[:0], data[whole:]...)
+
+	return data[:whole]
 }
 
 // Read reads converted audio data from the Task.
@@ -523,8 +696,8 @@ func (c *Task) Read(b []byte) (int, error) {
 		return 0, fmt.Errorf("read only available in stream mode")
 	}
 
-	if !c.streamStarted {
-		return 0, fmt.Errorf("stream not started, call Start() first")
+	if c.State() == TaskIdle {
+		return 0, &StateError{Op: "read", State: TaskIdle}
 	}
 
 	c.streamLock.Lock()
@@ -557,20 +730,57 @@ func (c *Task) Read(b []byte) (int, error) {
 //	}
 //	defer task.Stop()
 func (c *Task) Start() error {
+	if !c.tickerMode && !c.streamMode {
+		return fmt.Errorf("start only available in stream or ticker mode")
+	}
+
+	// Take the output type from the output path, as Convert does
+	c.outputType = ""
+	if c.outputPath != "" {
+		if err := c.inferOutputType(c.outputPath); err != nil {
+			return err
+		}
+	}
+
 	if c.tickerMode {
-		return c.runTicker()
+		if err := c.validateTicker(); err != nil {
+			return err
+		}
 	}
 
-	if !c.streamMode {
-		return fmt.Errorf("start only available in stream or ticker mode")
+	// Conversions validate on every run; a stream runs sox once, so check up front
+	if c.streamMode {
+		if err := c.validateFormats(); err != nil {
+			return err
+		}
+	}
+
+	if c.frameAligned && c.Input.FrameSize() == 0 {
+		return fmt.Errorf("frame alignment requires input bit depth and channels")
 	}
 
-	if c.streamStarted {
-		return fmt.Errorf("stream already started")
+	if err := c.transition("start", TaskRunning, TaskIdle, TaskStopped); err != nil {
+		return err
 	}
 
-	c.streamStarted = true
-	c.streamClosed = false
+	var err error
+	if c.tickerMode {
+		err = c.runTicker()
+	} else {
+		err = c.startStream()
+	}
+
+	if err != nil {
+		c.setState(TaskFailed)
+	}
+
+	return err
+}
+
+// startStream starts the SoX process with stdin/stdout pipes
+func (c *Task) startStream() error {
+	c.streamBuffer.Reset()
+	c.writeCarry = nil
 	c.streamOutput = &bytes.Buffer{}
 	c.streamOutputDone = make(chan error, 1)
 
@@ -578,7 +788,12 @@ func (c *Task) Start() error {
 	args := c.buildCommandArgs()
 
 	// Create command
-	cmd := exec.Command(c.Options.SoxPath, args...)
+	soxPath, err := FindSox(c.Options.SoxPath)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(soxPath, args...)
 
 	// Set up pipes
 	stdin, err := cmd.StdinPipe()
@@ -594,8 +809,9 @@ func (c *Task) Start() error {
 	c.streamStdout = stdout
 
 	// Capture stderr
-	var stderr bytes.Buffer
-	cmd.Stderr = &stderr
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	c.streamStderr = stderr
 
 	if err := cmd.Start(); err != nil {
 		return fmt.Errorf("failed to start sox: %w", err)
@@ -603,53 +819,75 @@ func (c *Task) Start() error {
 
 	c.streamCmd = cmd
 
+	// Capture per-run state so goroutines of a previous run never touch a restarted stream
+	output := c.streamOutput
+	done := c.streamOutputDone
+
 	// Start goroutine to continuously read stdout
 	// For RAW format with outputPath in stream mode, write to file in append mode
 	// Otherwise, buffer output in memory
-	if c.outputPath != "" && c.Output.Type == TYPE_RAW {
+	if c.outputPath != "" && strings.EqualFold(c.outputFormat().Type, TYPE_RAW) {
 		// Stream mode with outputPath and RAW format: read from stdout and append to file
 		// RAW format doesn't have headers, so we can safely append chunks
 		go func() {
 			file, err := os.OpenFile(c.outputPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
 			if err != nil {
-				c.streamOutputDone <- fmt.Errorf("failed to open output file: %w", err)
+				done <- fmt.Errorf("failed to open output file: %w", err)
 				return
 			}
 			defer file.Close()
 
 			_, err = io.Copy(file, stdout)
-			c.streamOutputDone <- err
+			done <- err
 		}()
 	} else {
 		// No outputPath or non-RAW format: buffer output in memory
 		// Formats with headers (FLAC, WAV) are written directly by sox to the file
 		go func() {
-			_, err := io.Copy(c.streamOutput, stdout)
-			c.streamOutputDone <- err
+			_, err := io.Copy(output, stdout)
+			done <- err
 		}()
 	}
 
 	return nil
 }
 
-// runTicker initializes the ticker-based conversion
-func (c *Task) runTicker() error {
+// validateTicker checks the ticker configuration before starting
+func (c *Task) validateTicker() error {
 	if c.tickerDuration <= 0 {
 		return fmt.Errorf("ticker duration must be positive")
 	}
 
-	c.ticker = time.NewTicker(c.tickerDuration)
+	return c.validateOverlap()
+}
+
+// runTicker initializes the ticker-based conversion
+func (c *Task) runTicker() error {
+	c.tickerLock.Lock()
+	defer c.tickerLock.Unlock()
+
+	c.resetTickerState()
+
+	// Each run gets its own ticker, channels and worker, so the Task can be restarted
+	ticker := time.NewTicker(c.tickerDuration)
+	stop := make(chan struct{})
+
+	c.ticker = ticker
+	c.tickerStop = stop
+	c.tickerQueue = make(chan *tickerFlush, c.tickerMaxInFlight)
+	c.tickerSlots = make(chan struct{}, c.tickerMaxInFlight)
+	c.tickerDone = make(chan struct{})
+
+	go c.runTickerWorker(c.tickerQueue, c.tickerDone)
 
 	go func() {
 		for {
 			select {
-			case <-c.ticker.C:
-				c.tickerLock.Lock()
-				if c.tickerBuffer.Len() > 0 {
-					_ = c.flushTickerBuffer()
-				}
-				c.tickerLock.Unlock()
-			case <-c.tickerStop:
+			case <-ticker.C:
+				// Never wait for the worker on a tick: if too many flushes are in
+				// flight, the pending audio is carried over to the next tick
+				_, _ = c.dispatchTickerFlush(context.Background(), false, nil)
+			case <-stop:
 				return
 			}
 		}
@@ -658,35 +896,6 @@ func (c *Task) runTicker() error {
 	return nil
 }
 
-// flushTickerBuffer converts buffered data (assumes lock is held)
-func (c *Task) flushTickerBuffer() error {
-	if c.tickerBuffer.Len() == 0 {
-		return nil
-	}
-
-	// Create a copy of buffer data
-	inputData := make([]byte, c.tickerBuffer.Len())
-	copy(inputData, c.tickerBuffer.Bytes())
-
-	// Reset buffer after copying to avoid duplicate processing
-	// c.tickerBuffer.Reset()
-
-	// Run conversion on copied data
-	ctx := context.Background()
-
-	if c.Options.Timeout > 0 {
-		var cancel context.CancelFunc
-		ctx, cancel = context.WithTimeout(ctx, c.Options.Timeout)
-
-		defer cancel()
-	}
-
-	inputReader := newBytesReader(inputData)
-	outputBuffer := &bytes.Buffer{}
-
-	return c.convertInternal(ctx, inputReader, outputBuffer)
-}
-
 // flushStreamBuffer writes the buffered stream data to the output path
 func (c *Task) flushStreamBuffer() error {
 	c.streamLock.Lock()
@@ -729,20 +938,37 @@ func (c *Task) flushStreamBuffer() error {
 //
 //	// Use task...
 func (c *Task) Stop() error {
-	if c.tickerMode {
-		return c.stopTicker()
+	if !c.tickerMode && !c.streamMode {
+		return nil
 	}
 
-	if !c.streamMode {
+	// Stopping an idle, stopped or failed Task is a no-op
+	if err := c.transition("stop", TaskStopping, TaskRunning); err != nil {
+		if c.State() == TaskStopping {
+			return err
+		}
+
 		return nil
 	}
 
-	if !c.streamStarted || c.streamClosed {
-		return nil
+	var err error
+	if c.tickerMode {
+		err = c.stopTicker()
+	} else {
+		err = c.stopStream()
 	}
 
-	c.streamClosed = true
+	if err != nil {
+		c.setState(TaskFailed)
+	} else {
+		c.setState(TaskStopped)
+	}
+
+	return err
+}
 
+// stopStream closes stdin and waits for the SoX process to finish
+func (c *Task) stopStream() error {
 	// Close stdin to signal EOF
 	if c.streamStdin != nil {
 		if err := c.streamStdin.Close(); err != nil {
@@ -762,6 +988,10 @@ func (c *Task) Stop() error {
 		<-c.streamOutputDone
 	}
 
+	if c.streamCmd != nil && c.streamStderr != nil {
+		return c.finishRun(c.streamStderr.Bytes())
+	}
+
 	// Flush to output path if configured in stream mode
 	// if c.outputPath != "" {
 	// 	return c.flushStreamBuffer()
@@ -770,18 +1000,33 @@ func (c *Task) Stop() error {
 	return nil
 }
 
-// stopTicker stops the ticker and flushes remaining data
+// stopTicker stops the ticker, flushes remaining data and waits for the flush worker
 func (c *Task) stopTicker() error {
 	if c.ticker != nil {
 		c.ticker.Stop()
 		close(c.tickerStop)
+		c.ticker = nil
 	}
 
 	// Final flush
+	if _, err := c.dispatchTickerFlush(context.Background(), true, nil); err != nil {
+		return err
+	}
+
+	c.tickerLock.Lock()
+	close(c.tickerQueue)
+	c.tickerQueue = nil
+	c.tickerLock.Unlock()
+
+	<-c.tickerDone
+
 	c.tickerLock.Lock()
 	defer c.tickerLock.Unlock()
 
-	return c.flushTickerBuffer()
+	err := errors.Join(c.tickerErrs...)
+	c.tickerErrs = nil
+
+	return err
 }
 
 // Close is an alias for Stop(), provided for compatibility with io.Closer.
@@ -801,14 +1046,9 @@ func (c *Task) executeWithRetry(ctx context.Context, inputPath, outputPath strin
 		default:
 		}
 
-		var err error
-		if c.circuitBreaker != nil {
-			err = c.circuitBreaker.Call(func() error {
-				return c.convertInternalPath(ctx)
-			})
-		} else {
-			err = c.convertInternalPath(ctx)
-		}
+		err := c.callBreaker(func() error {
+			return c.convertInternalPath(ctx)
+		})
 
 		if err == nil {
 			return nil
@@ -820,7 +1060,7 @@ func (c *Task) executeWithRetry(ctx context.Context, inputPath, outputPath strin
 			return err
 		}
 
-		if err == ErrInvalidFormat {
+		if errors.Is(err, ErrInvalidFormat) || errors.Is(err, ErrClipped) {
 			return err
 		}
 
@@ -855,14 +1095,9 @@ func (c *Task) executeWithRetryStream(ctx context.Context, input io.ReadSeeker,
 		default:
 		}
 
-		var err error
-		if c.circuitBreaker != nil {
-			err = c.circuitBreaker.Call(func() error {
-				return c.convertInternal(ctx, input, output)
-			})
-		} else {
-			err = c.convertInternal(ctx, input, output)
-		}
+		err := c.callBreaker(func() error {
+			return c.convertInternal(ctx, input, output)
+		})
 
 		if err == nil {
 			return nil
@@ -874,7 +1109,7 @@ func (c *Task) executeWithRetryStream(ctx context.Context, input io.ReadSeeker,
 			return err
 		}
 
-		if err == ErrInvalidFormat {
+		if errors.Is(err, ErrInvalidFormat) || errors.Is(err, ErrClipped) {
 			return err
 		}
 
@@ -902,90 +1137,228 @@ func (c *Task) executeWithRetryStream(ctx context.Context, input io.ReadSeeker,
 	return fmt.Errorf("conversion failed after %d attempts: %w", c.retryConfig.MaxAttempts, lastErr)
 }
 
-// convertInternal performs the actual SoX conversion without retry logic
-func (c *Task) convertInternal(ctx context.Context, input io.Reader, output io.Writer) error {
-	if err := c.Input.Validate(); err != nil {
-		return ErrInvalidFormat
+// callBreaker runs a conversion attempt through the circuit breaker, when there is
+// one. Clipping rejected by FailOnClip is returned without counting as a sox failure.
+func (c *Task) callBreaker(fn func() error) error {
+	if c.circuitBreaker == nil {
+		return fn()
 	}
 
-	if err := c.Output.Validate(); err != nil {
-		return ErrInvalidFormat
+	var clipped error
+	err := c.circuitBreaker.Call(func() error {
+		err := fn()
+		if errors.Is(err, ErrClipped) {
+			clipped = err
+			return nil
+		}
+		return err
+	})
+
+	if clipped != nil {
+		return clipped
 	}
 
-	args := c.buildCommandArgs()
-	cmd := exec.CommandContext(ctx, c.Options.SoxPath, args...)
+	return err
+}
 
-	cmd.Stdin = input
-	cmd.Stdout = output
+// inferOutputType takes the output type from the extension of path when Output.Type
+// is empty, and checks that an explicit Output.Type agrees with the extension
+func (c *Task) inferOutputType(path string) error {
+	if c.Output.Type == "" {
+		c.outputType = typeFromExtension(path)
+		return nil
+	}
 
-	stderr, err := cmd.StderrPipe()
-	if err != nil {
-		return fmt.Errorf("failed to create stderr pipe: %w", err)
+	if !extensionMatchesType(path, c.Output.Type) {
+		return fmt.Errorf("%w: %q has extension %q but Output.Type is %q",
+			ErrExtensionMismatch, path, filepath.Ext(path), c.Output.Type)
 	}
 
-	if err := cmd.Start(); err != nil {
-		return fmt.Errorf("failed to start sox: %w", err)
+	return nil
+}
+
+// convertInternal performs the actual SoX conversion without retry logic
+func (c *Task) convertInternal(ctx context.Context, input io.Reader, output io.Writer) error {
+	return c.runSox(ctx, c.buildCommandArgs(), input, output)
+}
+
+// convertInternalPath performs the actual SoX conversion for path-based mode
+func (c *Task) convertInternalPath(ctx context.Context) error {
+	// No stdin/stdout for path-based conversion
+	return c.runSox(ctx, c.buildCommandArgs(), nil, nil)
+}
+
+// convertPipe converts input to output through stdin/stdout pipes,
+// regardless of the configured output path
+func (c *Task) convertPipe(ctx context.Context, input io.Reader, output io.Writer) error {
+	return c.runSox(ctx, c.buildArgs("-", "-"), input, output)
+}
+
+// runSox validates the formats and executes sox with the given arguments
+func (c *Task) runSox(ctx context.Context, args []string, input io.Reader, output io.Writer) error {
+	if err := c.validateFormats(); err != nil {
+		return err
 	}
 
-	stderrData := make(chan []byte, 1)
-	go func() {
-		data, _ := io.ReadAll(stderr)
-		stderrData <- data
-	}()
+	stderr, err := c.execSox(ctx, "conversion", args, input, output)
+	if err != nil {
+		return err
+	}
 
-	if err := cmd.Wait(); err != nil {
-		errMsg := <-stderrData
+	return c.finishRun(stderr)
+}
 
-		if ctx.Err() != nil {
-			return fmt.Errorf("sox conversion timeout/cancelled: %w", ctx.Err())
-		}
+// validateFormats checks the Input and Output formats for their roles and the
+// encoder settings against the output type
+func (c *Task) validateFormats() error {
+	inputFormat := c.inputFormat()
+	if err := inputFormat.ValidateFor(RoleInput); err != nil {
+		return fmt.Errorf("input format: %w", err)
+	}
 
-		return fmt.Errorf("sox conversion failed: %w\nstderr: %s", err, string(errMsg))
+	outputFormat := c.outputFormat()
+	if err := outputFormat.ValidateFor(RoleOutput); err != nil {
+		return fmt.Errorf("output format: %w", err)
+	}
+
+	if err := c.validateEncoder(&outputFormat); err != nil {
+		return fmt.Errorf("output format: %w", err)
 	}
 
 	return nil
 }
 
-// convertInternalPath performs the actual SoX conversion for path-based mode
-func (c *Task) convertInternalPath(ctx context.Context) error {
-	if err := c.Input.Validate(); err != nil {
-		return ErrInvalidFormat
-	}
+// guardedCall runs a single sox operation that is not a conversion (no retries),
+// applying Options.Timeout and going through the circuit breaker
+func (c *Task) guardedCall(ctx context.Context, fn func(ctx context.Context) error) error {
+	if c.Options.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Options.Timeout)
 
-	if err := c.Output.Validate(); err != nil {
-		return ErrInvalidFormat
+		defer cancel()
 	}
 
-	args := c.buildCommandArgs()
-	cmd := exec.CommandContext(ctx, c.Options.SoxPath, args...)
+	if c.circuitBreaker == nil {
+		return fn(ctx)
+	}
 
-	cmd.Stdin = nil  // No stdin for path-based conversion
-	cmd.Stdout = nil // No stdout for path-based conversion
+	return c.circuitBreaker.Call(func() error {
+		return fn(ctx)
+	})
+}
 
-	stderr, err := cmd.StderrPipe()
+// soxWaitDelay bounds how long a finished or cancelled sox run waits for its output pipes
+const soxWaitDelay = time.Second
 
+// execSox runs sox with the given arguments and returns what it wrote to stderr.
+// Errors name the operation, e.g. "sox conversion failed", and include stderr.
+func (c *Task) execSox(ctx context.Context, op string, args []string, input io.Reader, output io.Writer) (_ []byte, err error) {
+	soxPath, err := FindSox(c.Options.SoxPath)
 	if err != nil {
-		return fmt.Errorf("failed to create stderr pipe: %w", err)
+		return nil, err
 	}
 
-	if err := cmd.Start(); err != nil {
-		return fmt.Errorf("failed to start sox: %w", err)
+	cmd := exec.CommandContext(ctx, soxPath, args...)
+
+	var stderr bytes.Buffer
+
+	// Record the run for ConvertWithResult, counting the bytes through the pipes
+	if trace := runTraceFrom(ctx); trace != nil {
+		var in *countingReader
+		if input != nil {
+			in = &countingReader{r: input}
+			input = in
+		}
+
+		var out *countingWriter
+		if output != nil {
+			out = &countingWriter{w: output}
+			output = out
+		}
+
+		start := time.Now()
+		defer func() {
+			trace.record(cmd, time.Since(start), in, out, stderr.Bytes(), err)
+		}()
 	}
 
-	stderrData := make(chan []byte, 1)
+	cmd.Stdin = input
+	cmd.Stdout = output
 
-	go func() {
-		data, _ := io.ReadAll(stderr)
-		stderrData <- data
-	}()
+	// Stop waiting for output once sox is gone, even if a child kept the pipes open
+	cmd.WaitDelay = soxWaitDelay
 
-	if err := cmd.Wait(); err != nil {
-		errMsg := <-stderrData
+	cmd.Stderr = &stderr
 
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start sox: %w", err)
+	}
+
+	if err := cmd.Wait(); err != nil {
 		if ctx.Err() != nil {
-			return fmt.Errorf("sox conversion timeout/cancelled: %w", ctx.Err())
+			return stderr.Bytes(), fmt.Errorf("sox %s timeout/cancelled: %w", op, ctx.Err())
 		}
-		return fmt.Errorf("sox conversion failed: %w\nstderr: %s", err, string(errMsg))
+
+		return stderr.Bytes(), fmt.Errorf("sox %s failed: %w\nstderr: %s", op, err, stderr.String())
+	}
+
+	return stderr.Bytes(), nil
+}
+
+// inputFormat returns Input with the sniffed type filled in when Type is empty
+func (c *Task) inputFormat() AudioFormat {
+	input := c.Input
+	if input.Type == "" {
+		input.Type = c.sniffedType
+	}
+
+	return input
+}
+
+// outputFormat returns Output with the inferred type filled in when Type is empty
+func (c *Task) outputFormat() AudioFormat {
+	output := c.Output
+	if output.Type == "" {
+		output.Type = c.outputType
+	}
+
+	return output
+}
+
+// encoderSettings returns the output encoder settings, falling back to the
+// deprecated CompressionLevel and Quality options for flac and ogg outputs
+func (c *Task) encoderSettings() EncoderSettings {
+	if c.encoder != nil {
+		return c.encoder
+	}
+
+	if c.Output.Compression != 0 {
+		return nil // -C already set explicitly on the output
+	}
+
+	switch strings.ToLower(c.outputFormat().Type) {
+	case TYPE_FLAC:
+		if c.Options.CompressionLevel >= 0 {
+			return FLACSettings{Level: c.Options.CompressionLevel}
+		}
+	case TYPE_OGG:
+		if c.Options.Quality >= 0 {
+			return VorbisSettings{Quality: float64(c.Options.Quality)}
+		}
+	}
+
+	return nil
+}
+
+// validateEncoder checks the encoder settings against the output format
+func (c *Task) validateEncoder(output *AudioFormat) error {
+	if c.encoder != nil && output.Compression != 0 {
+		return &FormatError{Field: "Compression", Value: output.Compression,
+			Reason: "conflicts with the encoder settings, which set the -C value"}
+	}
+
+	if encoder := c.encoderSettings(); encoder != nil {
+		return encoder.validateFor(output)
 	}
 
 	return nil
@@ -995,33 +1368,42 @@ func (c *Task) convertInternalPath(ctx context.Context) error {
 // For path mode: uses file paths directly (no pipes)
 // For stream/ticker mode: uses stdin/stdout pipes (-)
 func (c *Task) buildCommandArgs() []string {
+	// Path mode: use file paths directly (no piping needed)
+	if c.pathMode {
+		return c.buildArgs(c.inputPath, c.outputPath)
+	}
+
+	// Stream/ticker mode: use stdin/stdout pipes
+	// For stream mode with outputPath and RAW format, use stdout pipe for incremental append
+	// For other formats (FLAC, WAV, etc.) with headers, sox writes directly to file
+	// For ticker mode with outputPath, write directly to file
+	outputType := strings.ToLower(c.outputFormat().Type)
+	if c.outputPath != "" && c.streamMode && outputType != TYPE_FLAC && outputType != TYPE_WAV {
+		return c.buildArgs("-", "-") // stdout - we'll handle file writing in Go with append
+	} else if c.outputPath != "" {
+		return c.buildArgs("-", c.outputPath) // direct file output (required for formats with headers)
+	}
+
+	return c.buildArgs("-", "-") // stdin, stdout
+}
+
+// buildArgs constructs SoX arguments reading from inputTarget and writing to outputTarget,
+// where "-" stands for stdin or stdout, in the order sox requires:
+// global options, input options, input, output options, output, effects
+func (c *Task) buildArgs(inputTarget, outputTarget string) []string {
 	args := []string{}
 
-	args = append(args, c.Options.BuildGlobalArgs()...)
-	args = append(args, c.Input.BuildArgs()...)
+	input := c.inputFormat()
+	output := c.outputFormat()
 
-	// Path mode: use file paths directly (no piping needed)
-	if c.pathMode {
-		args = append(args, c.inputPath)
-		args = append(args, c.Output.BuildArgs()...)
-		args = append(args, c.outputPath)
-	} else {
-		// Stream/ticker mode: use stdin/stdout pipes
-		args = append(args, "-") // stdin
-
-		args = append(args, c.Output.BuildArgs()...)
-
-		// For stream mode with outputPath and RAW format, use stdout pipe for incremental append
-		// For other formats (FLAC, WAV, etc.) with headers, sox writes directly to file
-		// For ticker mode with outputPath, write directly to file
-		if c.outputPath != "" && c.streamMode && c.Output.Type != TYPE_FLAC && c.Output.Type != TYPE_WAV {
-			args = append(args, "-") // stdout - we'll handle file writing in Go with append
-		} else if c.outputPath != "" {
-			args = append(args, c.outputPath) // direct file output (required for formats with headers)
-		} else {
-			args = append(args, "-") // stdout
-		}
+	args = append(args, c.Options.BuildGlobalArgs()...)
+	args = append(args, input.BuildArgsFor(RoleInput)...)
+	args = append(args, inputTarget)
+	args = append(args, output.BuildArgsFor(RoleOutput)...)
+	if encoder := c.encoderSettings(); encoder != nil {
+		args = append(args, encoder.encoderArgs(&output)...)
 	}
+	args = append(args, outputTarget)
 
 	if effects := c.Options.buildEffectArgs(); len(effects) > 0 {
 		args = append(args, effects...)
@@ -1030,9 +1412,9 @@ func (c *Task) buildCommandArgs() []string {
 	return args
 }
 
-// CheckSoxInstalled verifies that SoX is installed and accessible.
-// If soxPath is empty, checks for "sox" in PATH.
-// Returns an error if SoX is not found or not executable.
+// CheckSoxInstalled verifies that SoX is installed, accessible and at least
+// MinSoxVersion. If soxPath is empty, sox is discovered as described in FindSox.
+// Returns an error matching ErrSoxNotFound, or a *VersionError, otherwise.
 //
 // Example:
 //
@@ -1045,14 +1427,6 @@ func (c *Task) buildCommandArgs() []string {
 //		log.Fatal("SoX not found at custom path:", err)
 //	}
 func CheckSoxInstalled(soxPath string) error {
-	if soxPath == "" {
-		soxPath = "sox"
-	}
-
-	cmd := exec.Command(soxPath, "--version")
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("sox not found or not executable: %w", err)
-	}
-
-	return nil
+	_, err := FindSox(soxPath)
+	return err
 }
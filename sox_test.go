@@ -3,11 +3,17 @@ package sox
 import (
 	"bytes"
 	"context"
+	"encoding/binary"
+	"encoding/json"
 	"errors"
+	"io"
 	"log"
+	"math"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
+	"sort"
 	"strings"
 	"sync"
 	"testing"
@@ -16,6 +22,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
+	"gopkg.in/yaml.v3"
 )
 
 // SoxTestSuite defines the test suite for Converter
@@ -44,22 +51,24 @@ func TestConverterSuite(t *testing.T) {
 
 // TestPresets tests format presets
 func (s *SoxTestSuite) TestPresets() {
-	presets := []AudioFormat{
-		PCM_RAW_8K_MONO,
-		WAV_16K_MONO,
-		ULAW_8K_MONO,
-	}
+	for _, name := range Presets() {
+		preset, ok := Preset(name)
+		require.True(s.T(), ok, name)
 
-	for _, preset := range presets {
-		s.Run(preset.Type, func() {
-			err := preset.Validate()
-			assert.NoError(s.T(), err, "Preset %s should be valid", preset.Type)
+		s.Run(name, func() {
+			err := preset.ValidateFor(RoleOutput)
+			assert.NoError(s.T(), err, "Preset %s should be valid", name)
 		})
 	}
 }
 
 // generatePCMData generates test PCM audio data
 func (s *SoxTestSuite) generatePCMData(sampleRate, durationMs int) []byte {
+	return generatePCMData(sampleRate, durationMs)
+}
+
+// generatePCMData creates 16-bit mono PCM test audio
+func generatePCMData(sampleRate, durationMs int) []byte {
 	numSamples := (sampleRate * durationMs) / 1000
 	buffer := make([]byte, numSamples*2) // mono, 16-bit
 	for i := 0; i < numSamples; i++ {
@@ -1299,6 +1308,2643 @@ func (s *SoxTestSuite) TestConvert_ExtensionCaseInsensitive() {
 	}
 }
 
+// TEST SUITE 8: Ticker Chunks
+// ═══════════════════════════════════════════════════════════
+
+// TestTicker_OverlapFrameAlignment verifies the overlap is rounded down to whole input frames
+func TestTicker_OverlapFrameAlignment(t *testing.T) {
+	task := New(PCM_RAW_8K_MONO, FLAC_16K_MONO_LE).
+		WithTicker(1 * time.Second).
+		WithOverlap(1500 * time.Millisecond)
+	assert.Equal(t, 24000, task.overlapBytes())
+
+	// 1601 bytes of 16-bit audio is aligned down to 1600
+	task.WithOverlap(100062500 * time.Nanosecond)
+	assert.Equal(t, 1600, task.overlapBytes())
+
+	stereo := PCM_RAW_8K_MONO
+	stereo.Channels = 2
+	task = New(stereo, FLAC_16K_MONO_LE).WithOverlap(10 * time.Millisecond)
+	assert.Equal(t, 320, task.overlapBytes())
+}
+
+// TestTicker_OverlapRequiresFrameInfo verifies Start fails when the overlap cannot be aligned
+func TestTicker_OverlapRequiresFrameInfo(t *testing.T) {
+	task := New(AudioFormat{Type: TYPE_WAV}, FLAC_16K_MONO_LE).
+		WithTicker(1 * time.Second).
+		WithOverlap(time.Second)
+
+	err := task.Start()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "overlap")
+}
+
+// TestTicker_ChunksWithOverlap verifies each chunk carries the tail of the preceding audio
+func (s *SoxTestSuite) TestTicker_ChunksWithOverlap() {
+	var chunks []Chunk
+
+	task := New(PCM_RAW_8K_MONO, FLAC_16K_MONO_LE).
+		WithTicker(time.Hour).
+		WithOverlap(200 * time.Millisecond).
+		WithChunkHandler(func(chunk Chunk) {
+			chunks = append(chunks, chunk)
+		})
+
+	require.NoError(s.T(), task.Start())
+
+	_, err := task.Write(s.generatePCMData(8000, 1000))
+	require.NoError(s.T(), err)
+
+	_, err = task.Flush(context.Background())
+	require.NoError(s.T(), err)
+
+	_, err = task.Write(s.generatePCMData(8000, 500))
+	require.NoError(s.T(), err)
+	require.NoError(s.T(), task.Stop())
+
+	require.Len(s.T(), chunks, 2)
+
+	assert.Equal(s.T(), 1, chunks[0].Seq)
+	assert.Equal(s.T(), time.Duration(0), chunks[0].Offset)
+	assert.Equal(s.T(), time.Second, chunks[0].Duration)
+	assert.Equal(s.T(), time.Duration(0), chunks[0].Overlap)
+	assert.NotEmpty(s.T(), chunks[0].Data)
+
+	assert.Equal(s.T(), 2, chunks[1].Seq)
+	assert.Equal(s.T(), 800*time.Millisecond, chunks[1].Offset)
+	assert.Equal(s.T(), 700*time.Millisecond, chunks[1].Duration)
+	assert.Equal(s.T(), 200*time.Millisecond, chunks[1].Overlap)
+	assert.Equal(s.T(), 3200, chunks[1].OverlapBytes)
+	assert.NotEmpty(s.T(), chunks[1].Data)
+}
+
+// TestTicker_OutputTemplateExpansion verifies template placeholders are resolved
+func TestTicker_OutputTemplateExpansion(t *testing.T) {
+	tmpDir := t.TempDir()
+	task := New(PCM_RAW_8K_MONO, FLAC_16K_MONO_LE).
+		WithOutputPath(filepath.Join(tmpDir, "call.flac")).
+		WithOutputTemplate("{dir}/{seq}-{unix}-{offset_ms}.flac")
+
+	path := task.expandOutputTemplate(7, 2500*time.Millisecond, time.Unix(1700000000, 0))
+	assert.Equal(t, filepath.Join(tmpDir, "000007-1700000000-2500.flac"), path)
+
+	task = New(PCM_RAW_8K_MONO, FLAC_16K_MONO_LE).WithOutputTemplate("{dir}/{seq}.flac")
+	assert.Equal(t, "./000001.flac", task.expandOutputTemplate(1, 0, time.Now()))
+}
+
+// TestTicker_PerFlushFilesWithManifest verifies one file and one manifest line per flush
+func (s *SoxTestSuite) TestTicker_PerFlushFilesWithManifest() {
+	manifestPath := filepath.Join(s.tmpDir, "index.jsonl")
+
+	task := New(PCM_RAW_8K_MONO, FLAC_16K_MONO_LE).
+		WithTicker(time.Hour).
+		WithOutputPath(filepath.Join(s.tmpDir, "chunks", "call.flac")).
+		WithOutputTemplate("{dir}/{seq}.flac").
+		WithManifest(manifestPath)
+
+	require.NoError(s.T(), task.Start())
+
+	_, err := task.Write(s.generatePCMData(8000, 1000))
+	require.NoError(s.T(), err)
+
+	_, err = task.Flush(context.Background())
+	require.NoError(s.T(), err)
+
+	_, err = task.Write(s.generatePCMData(8000, 500))
+	require.NoError(s.T(), err)
+	require.NoError(s.T(), task.Stop())
+
+	manifest, err := os.ReadFile(manifestPath)
+	require.NoError(s.T(), err)
+
+	lines := strings.Split(strings.TrimSpace(string(manifest)), "\n")
+	require.Len(s.T(), lines, 2)
+
+	var entries []ManifestEntry
+	for _, line := range lines {
+		var entry ManifestEntry
+		require.NoError(s.T(), json.Unmarshal([]byte(line), &entry))
+		entries = append(entries, entry)
+
+		info, err := os.Stat(entry.Path)
+		require.NoError(s.T(), err)
+		assert.Equal(s.T(), int64(entry.Bytes), info.Size())
+		assert.Len(s.T(), entry.SHA256, 64)
+	}
+
+	assert.Equal(s.T(), filepath.Join(s.tmpDir, "chunks", "000001.flac"), entries[0].Path)
+	assert.Equal(s.T(), int64(0), entries[0].OffsetMs)
+	assert.Equal(s.T(), int64(1000), entries[0].DurationMs)
+	assert.Equal(s.T(), 2, entries[1].Seq)
+	assert.Equal(s.T(), int64(1000), entries[1].OffsetMs)
+	assert.Equal(s.T(), int64(500), entries[1].DurationMs)
+
+	// The cumulative output file is not written when a template is set
+	_, err = os.Stat(filepath.Join(s.tmpDir, "chunks", "call.flac"))
+	assert.True(s.T(), os.IsNotExist(err))
+}
+
+// TestTicker_NonBlockingFlush verifies writes proceed while a flush converts and
+// that ticks beyond the in-flight limit carry their audio over to the next flush
+func (s *SoxTestSuite) TestTicker_NonBlockingFlush() {
+	release := make(chan struct{})
+	var chunks []Chunk
+
+	task := New(PCM_RAW_8K_MONO, FLAC_16K_MONO_LE).
+		WithTicker(time.Hour).
+		WithMaxInFlightFlushes(1).
+		WithChunkHandler(func(chunk Chunk) {
+			if chunk.Seq == 1 {
+				<-release
+			}
+			chunks = append(chunks, chunk)
+		})
+
+	require.NoError(s.T(), task.Start())
+
+	_, err := task.Write(s.generatePCMData(8000, 100))
+	require.NoError(s.T(), err)
+	_, err = task.dispatchTickerFlush(context.Background(), false, nil)
+	require.NoError(s.T(), err)
+
+	// The worker is busy with the first flush: writes must not wait for it
+	// and a second tick is skipped instead of queueing another flush
+	written := make(chan error, 1)
+	go func() {
+		_, err := task.Write(s.generatePCMData(8000, 100))
+		if err == nil {
+			_, err = task.dispatchTickerFlush(context.Background(), false, nil)
+		}
+		if err == nil {
+			_, err = task.Write(s.generatePCMData(8000, 100))
+		}
+		written <- err
+	}()
+
+	select {
+	case err := <-written:
+		require.NoError(s.T(), err)
+	case <-time.After(5 * time.Second):
+		s.T().Fatal("Write blocked while a flush was converting")
+	}
+
+	close(release)
+	require.NoError(s.T(), task.Stop())
+
+	require.Len(s.T(), chunks, 2)
+	assert.Equal(s.T(), 100*time.Millisecond, chunks[0].Duration)
+	assert.Equal(s.T(), 100*time.Millisecond, chunks[1].Offset)
+	assert.Equal(s.T(), 200*time.Millisecond, chunks[1].Duration)
+}
+
+// TestTicker_Flush verifies Flush returns converted audio and keeps the ticker running
+func (s *SoxTestSuite) TestTicker_Flush() {
+	var chunks []Chunk
+
+	task := New(PCM_RAW_8K_MONO, FLAC_16K_MONO_LE).
+		WithTicker(time.Hour).
+		WithChunkHandler(func(chunk Chunk) {
+			chunks = append(chunks, chunk)
+		})
+
+	_, err := task.Flush(context.Background())
+	assert.ErrorIs(s.T(), err, ErrInvalidState, "Flush requires a running task")
+
+	require.NoError(s.T(), task.Start())
+
+	data, err := task.Flush(context.Background())
+	require.NoError(s.T(), err)
+	assert.Nil(s.T(), data, "Nothing buffered yet")
+
+	_, err = task.Write(s.generatePCMData(8000, 500))
+	require.NoError(s.T(), err)
+
+	output := &bytes.Buffer{}
+	require.NoError(s.T(), task.FlushTo(context.Background(), output))
+	assert.Greater(s.T(), output.Len(), 0)
+	assert.Equal(s.T(), TaskRunning, task.State())
+
+	_, err = task.Write(s.generatePCMData(8000, 250))
+	require.NoError(s.T(), err)
+	require.NoError(s.T(), task.Stop())
+
+	require.Len(s.T(), chunks, 2)
+	assert.Equal(s.T(), output.Bytes(), chunks[0].Data)
+	assert.Equal(s.T(), 500*time.Millisecond, chunks[1].Offset)
+	assert.Equal(s.T(), 250*time.Millisecond, chunks[1].Duration)
+}
+
+// TestTicker_FlushCumulativeOutput verifies Flush returns only new audio while the output file keeps the whole recording
+func (s *SoxTestSuite) TestTicker_FlushCumulativeOutput() {
+	outputPath := filepath.Join(s.tmpDir, "recording.flac")
+
+	task := New(PCM_RAW_8K_MONO, FLAC_16K_MONO_LE).
+		WithTicker(time.Hour).
+		WithOutputPath(outputPath)
+	require.NoError(s.T(), task.Start())
+
+	_, err := task.Write(s.generatePCMData(8000, 500))
+	require.NoError(s.T(), err)
+
+	data, err := task.Flush(context.Background())
+	require.NoError(s.T(), err)
+	assert.NotEmpty(s.T(), data)
+
+	_, err = task.Write(s.generatePCMData(8000, 500))
+	require.NoError(s.T(), err)
+	require.NoError(s.T(), task.Stop())
+
+	info, err := os.Stat(outputPath)
+	require.NoError(s.T(), err)
+	assert.Greater(s.T(), info.Size(), int64(len(data)))
+}
+
+// TestTicker_TickKeepsAudioForFlush verifies ticks without a chunk handler, output
+// template or output path leave the audio buffered for Flush
+func TestTicker_TickKeepsAudioForFlush(t *testing.T) {
+	opts := DefaultOptions()
+	opts.SoxPath = fakeSox(t, "cat\n")
+
+	task := New(PCM_RAW_8K_MONO, PCM_RAW_8K_MONO).WithOptions(opts).WithTicker(10 * time.Millisecond)
+	require.NoError(t, task.Start())
+	defer task.Stop()
+
+	first := generatePCMData(8000, 100)
+	_, err := task.Write(first)
+	require.NoError(t, err)
+
+	time.Sleep(50 * time.Millisecond)
+
+	data, err := task.Flush(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, first, data)
+
+	second := generatePCMData(8000, 50)
+	_, err = task.Write(second)
+	require.NoError(t, err)
+
+	time.Sleep(50 * time.Millisecond)
+
+	data, err = task.Flush(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, second, data)
+}
+
+// TestTicker_FlushCancelled verifies the audio of a cancelled Flush is returned by
+// the next one
+func TestTicker_FlushCancelled(t *testing.T) {
+	tmpDir := t.TempDir()
+	block := filepath.Join(tmpDir, "block")
+	require.NoError(t, os.WriteFile(block, nil, 0644))
+
+	opts := DefaultOptions()
+	opts.SoxPath = fakeSox(t, `[ -f "`+block+`" ] && exec sleep 5
+cat
+`)
+
+	task := New(PCM_RAW_8K_MONO, PCM_RAW_8K_MONO).WithOptions(opts).WithTicker(time.Hour).DisableResilience()
+	require.NoError(t, task.Start())
+	defer task.Stop()
+
+	first := []byte{1, 2, 3, 4}
+	_, err := task.Write(first)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	_, err = task.Flush(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	require.NoError(t, os.Remove(block))
+
+	_, err = task.Write([]byte{5, 6})
+	require.NoError(t, err)
+
+	data, err := task.Flush(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []byte{1, 2, 3, 4, 5, 6}, data)
+}
+
+// TestTicker_WriteDuringStop verifies a Write racing Stop is either in the final
+// flush or rejected
+func TestTicker_WriteDuringStop(t *testing.T) {
+	opts := DefaultOptions()
+	opts.SoxPath = fakeSox(t, "cat\n")
+
+	var delivered int
+	task := New(PCM_RAW_8K_MONO, PCM_RAW_8K_MONO).WithOptions(opts).WithTicker(time.Hour).
+		WithChunkHandler(func(chunk Chunk) {
+			delivered += len(chunk.Data)
+		})
+	require.NoError(t, task.Start())
+
+	written := make(chan int)
+	go func() {
+		total := 0
+		for {
+			n, err := task.Write([]byte{1, 2})
+			if err != nil {
+				written <- total
+				return
+			}
+			total += n
+		}
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	require.NoError(t, task.Stop())
+
+	assert.Equal(t, <-written, delivered)
+}
+
+// TestTicker_FailedFlushKeepsAudio verifies a failed conversion keeps its audio and
+// chunk progress for the next flush, so the chunks have no gap
+func TestTicker_FailedFlushKeepsAudio(t *testing.T) {
+	fail := filepath.Join(t.TempDir(), "fail")
+	require.NoError(t, os.WriteFile(fail, nil, 0644))
+
+	opts := DefaultOptions()
+	opts.SoxPath = fakeSox(t, `if [ -f "`+fail+`" ]; then rm "`+fail+`"; exit 2; fi
+cat
+`)
+
+	var chunks []Chunk
+	task := New(PCM_RAW_8K_MONO, PCM_RAW_8K_MONO).WithOptions(opts).WithTicker(time.Hour).
+		WithOverlap(10 * time.Millisecond).
+		WithChunkHandler(func(chunk Chunk) {
+			chunks = append(chunks, chunk)
+		})
+	require.NoError(t, task.Start())
+
+	first := generatePCMData(8000, 100)
+	_, err := task.Write(first)
+	require.NoError(t, err)
+
+	_, err = task.Flush(context.Background())
+	require.Error(t, err)
+
+	second := generatePCMData(8000, 50)
+	_, err = task.Write(second)
+	require.NoError(t, err)
+
+	data, err := task.Flush(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, append(append([]byte(nil), first...), second...), data)
+
+	require.Len(t, chunks, 1)
+	assert.Equal(t, 1, chunks[0].Seq)
+	assert.Zero(t, chunks[0].Offset)
+	assert.Zero(t, chunks[0].OverlapBytes)
+	assert.Equal(t, 150*time.Millisecond, chunks[0].Duration)
+
+	require.NoError(t, task.Stop())
+}
+
+// TestTicker_ResetDuringFlush verifies a flush failing after a Reset leaves the
+// new run's chunk progress alone
+func TestTicker_ResetDuringFlush(t *testing.T) {
+	opts := DefaultOptions()
+	opts.SoxPath = fakeSox(t, "cat\n")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var task *Task
+	task = New(PCM_RAW_8K_MONO, PCM_RAW_8K_MONO).WithOptions(opts).WithTicker(time.Hour).
+		WithOutputPath(filepath.Join(t.TempDir(), "call.raw")).
+		WithOverlap(10 * time.Millisecond).
+		WithChunkHandler(func(chunk Chunk) {
+			// Fail the output rewrite that follows the chunk, after a Reset
+			cancel()
+			task.tickerLock.Lock()
+			task.resetTickerState()
+			task.tickerLock.Unlock()
+		})
+	require.NoError(t, task.Start())
+
+	_, err := task.Write(generatePCMData(8000, 100))
+	require.NoError(t, err)
+
+	_, err = task.Flush(ctx)
+	require.Error(t, err)
+
+	task.tickerLock.Lock()
+	assert.Zero(t, task.tickerSeq)
+	assert.Zero(t, task.tickerFlushed)
+	assert.Nil(t, task.tickerTail)
+	assert.Empty(t, task.tickerCarry)
+	task.tickerLock.Unlock()
+
+	require.NoError(t, task.Stop())
+}
+
+// TEST SUITE 9: Task Lifecycle
+// ═══════════════════════════════════════════════════════════
+
+// TestLifecycle_TickerRestart verifies a ticker can be started and stopped repeatedly
+func TestLifecycle_TickerRestart(t *testing.T) {
+	task := New(PCM_RAW_8K_MONO, FLAC_16K_MONO_LE).WithTicker(time.Hour)
+	assert.Equal(t, TaskIdle, task.State())
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, task.Start())
+		assert.Equal(t, TaskRunning, task.State())
+
+		_, err := task.Write(generatePCMData(8000, 100))
+		require.NoError(t, err)
+
+		require.NoError(t, task.Stop())
+		assert.Equal(t, TaskStopped, task.State())
+	}
+
+	require.NoError(t, task.Restart())
+	assert.Equal(t, TaskRunning, task.State())
+	require.NoError(t, task.Stop())
+}
+
+// TestLifecycle_StreamRestart verifies a stream can be started again after Stop
+func (s *SoxTestSuite) TestLifecycle_StreamRestart() {
+	task := New(PCM_RAW_8K_MONO, FLAC_16K_MONO_LE).WithStream()
+
+	for i := 0; i < 2; i++ {
+		require.NoError(s.T(), task.Start())
+
+		_, err := task.Write(s.generatePCMData(8000, 100))
+		require.NoError(s.T(), err)
+
+		require.NoError(s.T(), task.Stop())
+	}
+}
+
+// TestLifecycle_InvalidTransitions verifies calls in the wrong state return StateError
+func TestLifecycle_InvalidTransitions(t *testing.T) {
+	task := New(PCM_RAW_8K_MONO, FLAC_16K_MONO_LE).WithTicker(time.Hour)
+
+	_, err := task.Write([]byte{0, 0})
+	var stateErr *StateError
+	require.ErrorAs(t, err, &stateErr)
+	assert.Equal(t, "write", stateErr.Op)
+	assert.Equal(t, TaskIdle, stateErr.State)
+
+	require.NoError(t, task.Start())
+	err = task.Start()
+	assert.ErrorIs(t, err, ErrInvalidState)
+	assert.Equal(t, "cannot start: task is running", err.Error())
+
+	require.NoError(t, task.Stop())
+	assert.NoError(t, task.Stop(), "Stop should be idempotent")
+
+	_, err = task.Write([]byte{0, 0})
+	assert.ErrorIs(t, err, ErrInvalidState)
+}
+
+// TestLifecycle_FailedStart verifies a failed start must be reset before starting again
+func TestLifecycle_FailedStart(t *testing.T) {
+	tmpDir := t.TempDir()
+	opts := DefaultOptions()
+	opts.SoxPath = filepath.Join(tmpDir, "missing-sox")
+
+	task := New(PCM_RAW_8K_MONO, FLAC_16K_MONO_LE).WithOptions(opts).WithStream()
+	require.Error(t, task.Start())
+	assert.Equal(t, TaskFailed, task.State())
+
+	assert.ErrorIs(t, task.Start(), ErrInvalidState)
+
+	require.NoError(t, task.Reset())
+	assert.Equal(t, TaskIdle, task.State())
+}
+
+// TEST SUITE 10: Format Specs
+// ═══════════════════════════════════════════════════════════
+
+// TestFormatSpec_Parse verifies compact format specs are parsed into AudioFormat
+func TestFormatSpec_Parse(t *testing.T) {
+	testCases := []struct {
+		spec     string
+		expected AudioFormat
+	}{
+		{"raw:s16le@8000/1", AudioFormat{Type: TYPE_RAW, Encoding: SIGNED_INTEGER, BitDepth: 16, Endian: "little", SampleRate: 8000, Channels: 1}},
+		{"flac@16000/1", AudioFormat{Type: TYPE_FLAC, SampleRate: 16000, Channels: 1}},
+		{"raw:ulaw@8k/1", ULAW_8K_MONO},
+		{"wav:s24@44.1k/2", AudioFormat{Type: TYPE_WAV, Encoding: SIGNED_INTEGER, BitDepth: 24, SampleRate: 44100, Channels: 2}},
+		{"raw:f32be@48000", AudioFormat{Type: TYPE_RAW, Encoding: FLOATING_POINT, BitDepth: 32, Endian: "big", SampleRate: 48000}},
+		{"flac:16@16000/1", AudioFormat{Type: TYPE_FLAC, BitDepth: 16, SampleRate: 16000, Channels: 1}},
+		{"MP3", AudioFormat{Type: TYPE_MP3}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.spec, func(t *testing.T) {
+			f, err := ParseFormat(tc.spec)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, f)
+		})
+	}
+}
+
+// TestFormatSpec_ParseErrors verifies malformed specs are rejected
+func TestFormatSpec_ParseErrors(t *testing.T) {
+	for _, spec := range []string{"", "raw:x16@8000", "raw@abc/1", "raw@8000/0", "raw@8000/x"} {
+		_, err := ParseFormat(spec)
+		assert.ErrorIs(t, err, ErrInvalidFormat, "spec %q should be rejected", spec)
+	}
+}
+
+// TestFormatSpec_RoundTrip verifies String, text, JSON and YAML round-trip
+func TestFormatSpec_RoundTrip(t *testing.T) {
+	assert.Equal(t, "raw:s16@8000/1", PCM_RAW_8K_MONO.String())
+	assert.Equal(t, "raw:ulaw@8000/1", ULAW_8K_MONO.String())
+
+	type config struct {
+		Input  AudioFormat `json:"input" yaml:"input"`
+		Output AudioFormat `json:"output" yaml:"output"`
+	}
+
+	output := WAV_16K_MONO
+	output.Comment = "recorded by go-sox"
+	in := config{Input: ULAW_8K_MONO, Output: output}
+
+	data, err := json.Marshal(in)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"input":"raw:ulaw@8000/1"`)
+
+	var out config
+	require.NoError(t, json.Unmarshal(data, &out))
+	assert.Equal(t, in, out)
+
+	data, err = yaml.Marshal(in)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "input: raw:ulaw@8000/1")
+
+	out = config{}
+	require.NoError(t, yaml.Unmarshal(data, &out))
+	assert.Equal(t, in, out)
+
+	var f AudioFormat
+	require.NoError(t, f.UnmarshalText([]byte("flac@16000/1")))
+	text, err := f.MarshalText()
+	require.NoError(t, err)
+	assert.Equal(t, "flac@16000/1", string(text))
+}
+
+// TEST SUITE 11: RTP Mapping
+// ═══════════════════════════════════════════════════════════
+
+// TestRTP_FormatFromRTPMap verifies SDP rtpmap attributes map to AudioFormat
+func TestRTP_FormatFromRTPMap(t *testing.T) {
+	testCases := []struct {
+		rtpmap   string
+		expected AudioFormat
+	}{
+		{"a=rtpmap:0 PCMU/8000", ULAW_8K_MONO},
+		{"a=rtpmap:8 PCMA/8000", AudioFormat{Type: TYPE_RAW, Encoding: A_LAW, BitDepth: 8, SampleRate: 8000, Channels: 1}},
+		{"a=rtpmap:96 L16/16000/2", AudioFormat{Type: TYPE_RAW, Encoding: SIGNED_INTEGER, BitDepth: 16, Endian: "big", SampleRate: 16000, Channels: 2}},
+		{"rtpmap:3 GSM/8000", AudioFormat{Type: TYPE_GSM, SampleRate: 8000, Channels: 1}},
+		{"pcmu/8000", ULAW_8K_MONO},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.rtpmap, func(t *testing.T) {
+			f, err := FormatFromRTPMap(tc.rtpmap)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, f)
+		})
+	}
+
+	_, err := FormatFromRTPMap("a=rtpmap:18 G729/8000")
+	assert.ErrorIs(t, err, ErrUnsupportedCodec)
+	assert.Contains(t, err.Error(), "G729")
+
+	_, err = FormatFromRTPMap("a=rtpmap:101 telephone-event/8000")
+	assert.ErrorIs(t, err, ErrUnsupportedCodec)
+
+	for _, rtpmap := range []string{"a=rtpmap:0 PCMU", "a=rtpmap:0 PCMU/x", "a=rtpmap:96 L16/16000/0"} {
+		_, err := FormatFromRTPMap(rtpmap)
+		assert.Error(t, err, "rtpmap %q should be rejected", rtpmap)
+	}
+}
+
+// TestRTP_FormatFromPayloadType verifies the static payload types of RFC 3551
+func TestRTP_FormatFromPayloadType(t *testing.T) {
+	f, err := FormatFromPayloadType(0)
+	require.NoError(t, err)
+	assert.Equal(t, ULAW_8K_MONO, f)
+
+	f, err = FormatFromPayloadType(11)
+	require.NoError(t, err)
+	assert.Equal(t, 44100, f.SampleRate)
+	assert.Equal(t, 1, f.Channels)
+	assert.Equal(t, "big", f.Endian)
+
+	for _, pt := range []int{4, 5, 9, 13, 18} {
+		_, err := FormatFromPayloadType(pt)
+		assert.ErrorIs(t, err, ErrUnsupportedCodec, "payload type %d", pt)
+	}
+
+	_, err = FormatFromPayloadType(96)
+	assert.Error(t, err)
+	_, err = FormatFromPayloadType(50)
+	assert.Error(t, err)
+}
+
+// TestRTP_RTPMap verifies the reverse mapping used to generate SDP
+func TestRTP_RTPMap(t *testing.T) {
+	pt, ok := ULAW_8K_MONO.PayloadType()
+	assert.True(t, ok)
+	assert.Equal(t, 0, pt)
+
+	line, err := ULAW_8K_MONO.RTPMap(96)
+	require.NoError(t, err)
+	assert.Equal(t, "a=rtpmap:0 PCMU/8000", line)
+
+	l16, err := FormatFromRTPMap("a=rtpmap:96 L16/16000/2")
+	require.NoError(t, err)
+	_, ok = l16.PayloadType()
+	assert.False(t, ok)
+
+	line, err = l16.RTPMap(97)
+	require.NoError(t, err)
+	assert.Equal(t, "a=rtpmap:97 L16/16000/2", line)
+
+	_, err = l16.RTPMap(8)
+	assert.Error(t, err, "dynamic payload type outside 96-127")
+
+	_, err = PCM_RAW_8K_MONO.RTPMap(96)
+	assert.Error(t, err, "L16 requires big-endian samples")
+
+	_, err = WAV_16K_MONO.RTPMap(96)
+	assert.ErrorIs(t, err, ErrUnsupportedCodec)
+}
+
+// TestRTP_Aliases verifies encoding aliases and type case map to payload formats
+func TestRTP_Aliases(t *testing.T) {
+	pt, ok := (&AudioFormat{Type: "RAW", Encoding: "ulaw", SampleRate: 8000, BitDepth: 8, Channels: 1}).PayloadType()
+	assert.True(t, ok)
+	assert.Equal(t, 0, pt)
+
+	line, err := (&AudioFormat{Type: "raw", Encoding: "alaw", SampleRate: 8000, BitDepth: 8}).RTPMap(96)
+	require.NoError(t, err)
+	assert.Equal(t, "a=rtpmap:8 PCMA/8000", line)
+
+	line, err = (&AudioFormat{Type: "raw", Encoding: "signed", SampleRate: 16000, BitDepth: 16, Channels: 2, Endian: "big"}).RTPMap(97)
+	require.NoError(t, err)
+	assert.Equal(t, "a=rtpmap:97 L16/16000/2", line)
+
+	line, err = (&AudioFormat{Type: "GSM", SampleRate: 8000}).RTPMap(96)
+	require.NoError(t, err)
+	assert.Equal(t, "a=rtpmap:3 GSM/8000", line)
+
+	_, err = (&AudioFormat{Type: "WAV", Encoding: "signed", SampleRate: 8000, BitDepth: 16}).RTPMap(96)
+	assert.ErrorIs(t, err, ErrUnsupportedCodec)
+}
+
+// TEST SUITE 12: Format Sniffing
+// ═══════════════════════════════════════════════════════════
+
+// buildWAV wraps PCM data in a minimal RIFF/WAVE header, with a JUNK chunk before fmt
+func buildWAV(pcm []byte, sampleRate, channels, bitDepth int) []byte {
+	var b bytes.Buffer
+	le := binary.LittleEndian
+	blockAlign := channels * bitDepth / 8
+
+	b.WriteString("RIFF")
+	binary.Write(&b, le, uint32(4+(8+28)+(8+16)+(8+len(pcm))))
+	b.WriteString("WAVE")
+	b.WriteString("JUNK")
+	binary.Write(&b, le, uint32(28))
+	b.Write(make([]byte, 28))
+	b.WriteString("fmt ")
+	binary.Write(&b, le, uint32(16))
+	binary.Write(&b, le, uint16(1))
+	binary.Write(&b, le, uint16(channels))
+	binary.Write(&b, le, uint32(sampleRate))
+	binary.Write(&b, le, uint32(sampleRate*blockAlign))
+	binary.Write(&b, le, uint16(blockAlign))
+	binary.Write(&b, le, uint16(bitDepth))
+	b.WriteString("data")
+	binary.Write(&b, le, uint32(len(pcm)))
+	b.Write(pcm)
+
+	return b.Bytes()
+}
+
+// TestSniff_DetectFormat verifies containers are recognized from their first bytes
+func TestSniff_DetectFormat(t *testing.T) {
+	// MPEG-1 Layer III, 128 kbps, 44100 Hz, joint stereo: 417-byte frames
+	mp3 := make([]byte, 417+4)
+	copy(mp3, []byte{0xFF, 0xFB, 0x90, 0x44})
+	copy(mp3[417:], []byte{0xFF, 0xFB, 0x90, 0x44})
+
+	// STREAMINFO: 16000 Hz, 1 channel, 16 bits
+	flac := append([]byte("fLaC"), 0x80, 0, 0, 34)
+	flac = append(flac, make([]byte, 10)...)
+	flac = append(flac, 0x03, 0xE8, 0x00, 0xF0)
+
+	au := []byte(".snd\x00\x00\x00\x18\xff\xff\xff\xff\x00\x00\x00\x01\x00\x00\x1f\x40\x00\x00\x00\x01")
+
+	testCases := []struct {
+		name     string
+		header   []byte
+		expected AudioFormat
+	}{
+		{"wav", buildWAV(nil, 16000, 2, 16), AudioFormat{Type: TYPE_WAV, Encoding: SIGNED_INTEGER, SampleRate: 16000, Channels: 2, BitDepth: 16}},
+		{"wav 8-bit", buildWAV(nil, 8000, 1, 8), AudioFormat{Type: TYPE_WAV, Encoding: UNSIGNED_INTEGER, SampleRate: 8000, Channels: 1, BitDepth: 8}},
+		{"flac", flac, AudioFormat{Type: TYPE_FLAC, SampleRate: 16000, Channels: 1, BitDepth: 16}},
+		{"ogg", []byte("OggS\x00\x02"), AudioFormat{Type: TYPE_OGG}},
+		{"opus", append([]byte("OggS"), append(make([]byte, 24), "OpusHead"...)...), AudioFormat{Type: TYPE_OPUS}},
+		{"id3", []byte("ID3\x04\x00"), AudioFormat{Type: TYPE_MP3}},
+		{"mpeg sync", mp3, AudioFormat{Type: TYPE_MP3, SampleRate: 44100, Channels: 2}},
+		{"aiff", []byte("FORM\x00\x00\x00\x00AIFF"), AudioFormat{Type: TYPE_AIFF}},
+		{"au", au, AudioFormat{Type: TYPE_AU, Encoding: MU_LAW, BitDepth: 8, SampleRate: 8000, Channels: 1}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			f, ok := DetectFormat(tc.header)
+			assert.True(t, ok)
+			assert.Equal(t, tc.expected, f)
+		})
+	}
+
+	// Raw audio, including a single MPEG-like sync word and μ-law silence, is not recognized
+	for _, header := range [][]byte{generatePCMData(8000, 100), mp3[:417], bytes.Repeat([]byte{0xFF}, 512), nil} {
+		_, ok := DetectFormat(header)
+		assert.False(t, ok)
+	}
+}
+
+// TestSniff_SniffFormatPreservesInput verifies sniffing does not consume the reader
+func TestSniff_SniffFormatPreservesInput(t *testing.T) {
+	wav := buildWAV(generatePCMData(8000, 500), 8000, 1, 16)
+
+	// Plain reader: peeked bytes are replayed
+	f, r, err := SniffFormat(io.MultiReader(bytes.NewReader(wav)))
+	require.NoError(t, err)
+	assert.Equal(t, TYPE_WAV, f.Type)
+	assert.Equal(t, 8000, f.SampleRate)
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, wav, data)
+
+	// Seekable reader: rewound and returned as is
+	seeker := bytes.NewReader(wav)
+	f, r, err = SniffFormat(seeker)
+	require.NoError(t, err)
+	assert.Equal(t, TYPE_WAV, f.Type)
+	assert.Same(t, seeker, r)
+	data, err = io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, wav, data)
+
+	// Short raw input
+	f, r, err = SniffFormat(io.MultiReader(bytes.NewReader([]byte{1, 2, 3})))
+	require.NoError(t, err)
+	assert.Empty(t, f.Type)
+	data, err = io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{1, 2, 3}, data)
+}
+
+// TestSniff_TaskWithoutInputType verifies Task sniffs readers when Input.Type is empty
+func (s *SoxTestSuite) TestSniff_TaskWithoutInputType() {
+	wav := buildWAV(s.generatePCMData(8000, 500), 8000, 1, 16)
+
+	task := New(AudioFormat{}, PCM_RAW_8K_MONO)
+	var output bytes.Buffer
+	require.NoError(s.T(), task.Convert(io.MultiReader(bytes.NewReader(wav)), &output))
+
+	args := strings.Join(task.buildCommandArgs(), " ")
+	assert.Contains(s.T(), args, "-t wav - -t raw")
+	assert.NotEmpty(s.T(), output.Bytes())
+
+	output.Reset()
+	require.NoError(s.T(), Convert(bytes.NewReader(wav), &output, PCM_RAW_8K_MONO))
+	assert.NotEmpty(s.T(), output.Bytes())
+}
+
+// TEST SUITE 13: Output Type Inference
+// ═══════════════════════════════════════════════════════════
+
+// TestOutputType_InferredFromPath verifies an empty Output.Type is taken from the output extension
+func TestOutputType_InferredFromPath(t *testing.T) {
+	tmpDir := t.TempDir()
+	inputPath := filepath.Join(tmpDir, "input.raw")
+	require.NoError(t, os.WriteFile(inputPath, generatePCMData(8000, 200), 0644))
+
+	testCases := []struct {
+		output   string
+		expected string
+	}{
+		{"out.flac", TYPE_FLAC},
+		{"out.WAV", TYPE_WAV},
+		{"out.aif", TYPE_AIFF},
+		{"out.pcm", TYPE_RAW},
+		{"out.unknown", ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.output, func(t *testing.T) {
+			task := New(PCM_RAW_8K_MONO, AudioFormat{SampleRate: 8000, Channels: 1})
+			task.DisableResilience()
+			_ = task.Convert(inputPath, filepath.Join(tmpDir, tc.output))
+
+			assert.Equal(t, tc.expected, task.outputType)
+			if tc.expected != "" {
+				assert.Contains(t, strings.Join(task.buildCommandArgs(), " "), "-t "+tc.expected+" -c 1")
+			}
+			assert.Empty(t, task.Output.Type, "Output is not modified")
+		})
+	}
+}
+
+// TestOutputType_ExplicitTypeWins verifies explicit types are kept and checked against the extension
+func TestOutputType_ExplicitTypeWins(t *testing.T) {
+	tmpDir := t.TempDir()
+	inputPath := filepath.Join(tmpDir, "input.raw")
+	require.NoError(t, os.WriteFile(inputPath, generatePCMData(8000, 200), 0644))
+
+	// Raw family extensions are compatible with raw output
+	for _, output := range []string{"out.ul", "out.sln", "out.raw", "out.bin"} {
+		task := New(PCM_RAW_8K_MONO, ULAW_8K_MONO).DisableResilience()
+		err := task.Convert(inputPath, filepath.Join(tmpDir, output))
+		assert.NotErrorIs(t, err, ErrExtensionMismatch, output)
+		assert.Empty(t, task.outputType)
+	}
+
+	task := New(PCM_RAW_8K_MONO, FLAC_16K_MONO_LE).DisableResilience()
+	err := task.Convert(inputPath, filepath.Join(tmpDir, "out.wav"))
+	assert.ErrorIs(t, err, ErrExtensionMismatch)
+	assert.Contains(t, err.Error(), `"flac"`)
+
+	err = Convert(inputPath, filepath.Join(tmpDir, "out.mp3"), Options{Type: TYPE_RAW})
+	assert.ErrorIs(t, err, ErrExtensionMismatch)
+}
+
+// TestOutputType_InferredOnStart verifies ticker and stream Tasks infer the output
+// type from WithOutputPath
+func TestOutputType_InferredOnStart(t *testing.T) {
+	tmpDir := t.TempDir()
+	task := New(PCM_RAW_8K_MONO, AudioFormat{SampleRate: 16000, Channels: 1}).
+		WithTicker(time.Hour).
+		WithOutputPath(filepath.Join(tmpDir, "recording.flac"))
+	require.NoError(t, task.Start())
+
+	assert.Equal(t, TYPE_FLAC, task.outputType)
+	assert.Contains(t, strings.Join(task.buildCommandArgs(), " "), "-t flac -c 1 -r 16000 "+task.outputPath)
+	require.NoError(t, task.Stop())
+
+	stream := New(PCM_RAW_8K_MONO, WAV_16K_MONO).WithStream().WithOutputPath(filepath.Join(tmpDir, "out.flac"))
+	assert.ErrorIs(t, stream.Start(), ErrExtensionMismatch)
+	assert.Equal(t, TaskIdle, stream.State())
+}
+
+// TEST SUITE 14: Encodings and Validation
+// ═══════════════════════════════════════════════════════════
+
+// TestEncoding_Aliases verifies encoding aliases resolve to sox names
+func TestEncoding_Aliases(t *testing.T) {
+	testCases := map[string]Encoding{
+		"signed":  SIGNED_INTEGER,
+		"Float":   FLOATING_POINT,
+		"ulaw":    MU_LAW,
+		"u-law":   MU_LAW,
+		"alaw":    A_LAW,
+		"ima":     IMA_ADPCM,
+		"gsm":     GSM_FULL_RATE,
+		"mu-law":  MU_LAW,
+		"a-law":   A_LAW,
+		"ms":      MS_ADPCM,
+		"oki":     OKI_ADPCM,
+		"integer": "",
+	}
+
+	for name, expected := range testCases {
+		enc, err := ParseEncoding(name)
+		if expected == "" {
+			assert.ErrorIs(t, err, ErrInvalidFormat, name)
+			continue
+		}
+		require.NoError(t, err, name)
+		assert.Equal(t, expected, enc, name)
+	}
+
+	format := AudioFormat{Type: TYPE_RAW, Encoding: "signed", BitDepth: 16}
+	assert.Equal(t, []string{"-t", "raw", "-e", "signed-integer", "-b", "16"}, format.BuildArgs())
+}
+
+// TestValidate_FieldErrors verifies invalid combinations are rejected with the offending field
+func TestValidate_FieldErrors(t *testing.T) {
+	testCases := []struct {
+		name   string
+		format AudioFormat
+		field  string
+	}{
+		{"unknown encoding", AudioFormat{Type: TYPE_RAW, Encoding: "little-endian"}, "Encoding"},
+		{"mu-law 16-bit", AudioFormat{Type: TYPE_RAW, Encoding: MU_LAW, BitDepth: 16}, "BitDepth"},
+		{"a-law 4-bit", AudioFormat{Type: TYPE_RAW, Encoding: A_LAW, BitDepth: 4}, "BitDepth"},
+		{"float 16-bit", AudioFormat{Type: TYPE_RAW, Encoding: FLOATING_POINT, BitDepth: 16}, "BitDepth"},
+		{"unsigned flac", AudioFormat{Type: TYPE_FLAC, Encoding: "unsigned", BitDepth: 16}, "Encoding"},
+		{"32-bit flac", AudioFormat{Type: TYPE_FLAC, BitDepth: 32}, "BitDepth"},
+		{"signed 8-bit wav", AudioFormat{Type: TYPE_WAV, Encoding: "signed", BitDepth: 8}, "Encoding"},
+		{"unsigned 16-bit wav", AudioFormat{Type: TYPE_WAV, Encoding: UNSIGNED_INTEGER, BitDepth: 16}, "Encoding"},
+		{"mp3 with encoding", AudioFormat{Type: TYPE_MP3, Encoding: SIGNED_INTEGER}, "Encoding"},
+		{"ima-adpcm au", AudioFormat{Type: TYPE_AU, Encoding: IMA_ADPCM, BitDepth: 4}, "Encoding"},
+		{"rate too low", AudioFormat{Type: TYPE_RAW, SampleRate: 100}, "SampleRate"},
+		{"rate too high", AudioFormat{Type: TYPE_RAW, SampleRate: 1000000}, "SampleRate"},
+		{"negative channels", AudioFormat{Type: TYPE_RAW, Channels: -1}, "Channels"},
+		{"too many channels", AudioFormat{Type: TYPE_RAW, Channels: 65}, "Channels"},
+		{"bad endian", AudioFormat{Type: TYPE_RAW, Endian: "middle"}, "Endian"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.format.Validate()
+			require.Error(t, err)
+			assert.ErrorIs(t, err, ErrInvalidFormat)
+
+			var formatErr *FormatError
+			require.ErrorAs(t, err, &formatErr)
+			assert.Equal(t, tc.field, formatErr.Field)
+			assert.Contains(t, err.Error(), tc.field)
+		})
+	}
+
+	valid := []AudioFormat{
+		{Type: TYPE_WAV, Encoding: UNSIGNED_INTEGER, BitDepth: 8},
+		{Type: TYPE_WAV, Encoding: MU_LAW, BitDepth: 8},
+		{Type: TYPE_WAV, Encoding: IMA_ADPCM, BitDepth: 4},
+		{Type: TYPE_AU, Encoding: A_LAW, BitDepth: 8},
+		{Type: "sln", Encoding: SIGNED_INTEGER, BitDepth: 16, SampleRate: 8000},
+		{Type: TYPE_MP3},
+		{},
+	}
+
+	for _, format := range valid {
+		assert.NoError(t, format.Validate(), "%+v", format)
+	}
+}
+
+// TestValidate_ConvertStopsOnInvalidFormat verifies Convert returns the field error without retrying
+func TestValidate_ConvertStopsOnInvalidFormat(t *testing.T) {
+	task := New(PCM_RAW_8K_MONO, AudioFormat{Type: TYPE_FLAC, Encoding: UNSIGNED_INTEGER, BitDepth: 16})
+	task.WithRetryConfig(RetryConfig{MaxAttempts: 3, InitialBackoff: time.Second, MaxBackoff: time.Second, BackoffMultiple: 1})
+
+	start := time.Now()
+	err := task.Convert(bytes.NewReader(generatePCMData(8000, 100)), &bytes.Buffer{})
+
+	assert.ErrorIs(t, err, ErrInvalidFormat)
+	assert.Contains(t, err.Error(), "output format")
+	assert.Contains(t, err.Error(), "Encoding")
+	assert.Less(t, time.Since(start), time.Second, "invalid formats are not retried")
+}
+
+// TEST SUITE 15: Format Roles
+// ═══════════════════════════════════════════════════════════
+
+// TestRoles_BuildArgsFor verifies role-specific options only land on their side
+func TestRoles_BuildArgsFor(t *testing.T) {
+	format := AudioFormat{
+		Type:         TYPE_FLAC,
+		Volume:       2,
+		IgnoreLength: true,
+		Compression:  5,
+		Comment:      "take 1",
+	}
+
+	inputArgs := format.BuildArgsFor(RoleInput)
+	assert.Equal(t, []string{"-v", "2.000000", "--ignore-length", "-t", "flac"}, inputArgs)
+
+	outputArgs := format.BuildArgsFor(RoleOutput)
+	assert.Equal(t, []string{"-t", "flac", "-C", "5.000000", "--comment", "take 1"}, outputArgs)
+}
+
+// TestRoles_ValidateFor verifies role-invalid fields are rejected with their name
+func TestRoles_ValidateFor(t *testing.T) {
+	testCases := []struct {
+		format AudioFormat
+		role   FormatRole
+		field  string
+	}{
+		{AudioFormat{Type: TYPE_FLAC, Volume: 0.5}, RoleOutput, "Volume"},
+		{AudioFormat{Type: TYPE_FLAC, IgnoreLength: true}, RoleOutput, "IgnoreLength"},
+		{AudioFormat{Type: TYPE_WAV, Compression: 8}, RoleInput, "Compression"},
+		{AudioFormat{Type: TYPE_WAV, Comment: "x"}, RoleInput, "Comment"},
+		{AudioFormat{Type: TYPE_WAV, AddComment: "x"}, RoleInput, "AddComment"},
+		{AudioFormat{Type: TYPE_WAV, CommentFile: "notes.txt"}, RoleInput, "CommentFile"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.field, func(t *testing.T) {
+			err := tc.format.ValidateFor(tc.role)
+			assert.ErrorIs(t, err, ErrInvalidFormat)
+
+			var formatErr *FormatError
+			require.ErrorAs(t, err, &formatErr)
+			assert.Equal(t, tc.field, formatErr.Field)
+			assert.Contains(t, err.Error(), "not valid on the "+tc.role.String())
+		})
+	}
+
+	input := AudioFormat{Type: TYPE_RAW, Volume: 0.5, IgnoreLength: true}
+	assert.NoError(t, input.ValidateFor(RoleInput))
+
+	output := AudioFormat{Type: TYPE_FLAC, Compression: 8, Comment: "x"}
+	assert.NoError(t, output.ValidateFor(RoleOutput))
+}
+
+// TestRoles_TaskRejectsMisplacedOptions verifies Convert fails before running sox
+func TestRoles_TaskRejectsMisplacedOptions(t *testing.T) {
+	output := FLAC_16K_MONO_LE
+	output.Volume = 2
+
+	task := New(PCM_RAW_8K_MONO, output)
+	err := task.Convert(bytes.NewReader(generatePCMData(8000, 100)), &bytes.Buffer{})
+	assert.ErrorIs(t, err, ErrInvalidFormat)
+	assert.Contains(t, err.Error(), "output format: invalid Volume")
+
+	args := strings.Join(task.buildCommandArgs(), " ")
+	assert.NotContains(t, args, "-v ")
+}
+
+// TestRoles_StreamRejectsMisplacedOptions verifies Start fails before running sox
+func TestRoles_StreamRejectsMisplacedOptions(t *testing.T) {
+	input := PCM_RAW_8K_MONO
+	input.Compression = 5
+
+	task := New(input, PCM_RAW_8K_MONO).WithStream()
+	err := task.Start()
+	assert.ErrorIs(t, err, ErrInvalidFormat)
+	assert.Contains(t, err.Error(), "input format: invalid Compression")
+	assert.Equal(t, TaskIdle, task.State())
+
+	task = New(PCM_RAW_8K_MONO, AudioFormat{Type: TYPE_WAV}).WithStream().WithEncoder(MP3Settings{Bitrate: 128})
+	assert.ErrorIs(t, task.Start(), ErrInvalidFormat)
+}
+
+// TEST SUITE 16: Encoder Settings
+// ═══════════════════════════════════════════════════════════
+
+// TestEncoder_Args verifies encoder settings are rendered as output options
+func TestEncoder_Args(t *testing.T) {
+	testCases := []struct {
+		name     string
+		output   AudioFormat
+		settings EncoderSettings
+		expected []string
+	}{
+		{"flac", AudioFormat{Type: TYPE_FLAC}, FLACSettings{Level: 8}, []string{"-C", "8"}},
+		{"mp3 cbr", AudioFormat{Type: TYPE_MP3}, MP3Settings{Bitrate: 128}, []string{"-C", "128"}},
+		{"mp3 cbr quality", AudioFormat{Type: TYPE_MP3}, MP3Settings{Bitrate: 192, Quality: 2}, []string{"-C", "192.2"}},
+		{"mp3 vbr", AudioFormat{Type: TYPE_MP3}, MP3Settings{VBR: true, VBRQuality: 4}, []string{"-C", "-4"}},
+		{"mp3 vbr 0", AudioFormat{Type: TYPE_MP3}, MP3Settings{VBR: true, Quality: 2}, []string{"-C", "-0.2"}},
+		{"vorbis", AudioFormat{Type: TYPE_OGG}, VorbisSettings{Quality: 6.5}, []string{"-C", "6.5"}},
+		{"gsm wav", AudioFormat{Type: TYPE_WAV}, GSMSettings{}, []string{"-e", "gsm-full-rate"}},
+		{"gsm file", AudioFormat{Type: TYPE_GSM}, GSMSettings{}, nil},
+		{"ima wav", AudioFormat{Type: TYPE_WAV}, ADPCMSettings{Encoding: "ima"}, []string{"-e", "ima-adpcm", "-b", "4"}},
+		{"oki raw", AudioFormat{Type: TYPE_RAW, BitDepth: 4}, ADPCMSettings{Encoding: OKI_ADPCM}, []string{"-e", "oki-adpcm"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.NoError(t, tc.settings.validateFor(&tc.output))
+			assert.Equal(t, tc.expected, tc.settings.encoderArgs(&tc.output))
+		})
+	}
+}
+
+// TestEncoder_Validation verifies settings are checked against the output type
+func TestEncoder_Validation(t *testing.T) {
+	testCases := []struct {
+		name     string
+		output   AudioFormat
+		settings EncoderSettings
+	}{
+		{"flac settings on wav", AudioFormat{Type: TYPE_WAV}, FLACSettings{Level: 5}},
+		{"flac level 9", AudioFormat{Type: TYPE_FLAC}, FLACSettings{Level: 9}},
+		{"mp3 bad bitrate", AudioFormat{Type: TYPE_MP3}, MP3Settings{Bitrate: 100}},
+		{"mp3 cbr and vbr", AudioFormat{Type: TYPE_MP3}, MP3Settings{Bitrate: 128, VBR: true}},
+		{"mp3 nothing set", AudioFormat{Type: TYPE_MP3}, MP3Settings{}},
+		{"mp3 vbr 0 without quality", AudioFormat{Type: TYPE_MP3}, MP3Settings{VBR: true}},
+		{"mp3 quality 10", AudioFormat{Type: TYPE_MP3}, MP3Settings{Bitrate: 128, Quality: 10}},
+		{"vorbis on mp3", AudioFormat{Type: TYPE_MP3}, VorbisSettings{Quality: 3}},
+		{"vorbis quality 11", AudioFormat{Type: TYPE_OGG}, VorbisSettings{Quality: 11}},
+		{"gsm 16k", AudioFormat{Type: TYPE_WAV, SampleRate: 16000}, GSMSettings{}},
+		{"gsm stereo", AudioFormat{Type: TYPE_GSM, Channels: 2}, GSMSettings{}},
+		{"gsm on flac", AudioFormat{Type: TYPE_FLAC}, GSMSettings{}},
+		{"ms adpcm raw", AudioFormat{Type: TYPE_RAW}, ADPCMSettings{Encoding: MS_ADPCM}},
+		{"adpcm 16-bit", AudioFormat{Type: TYPE_WAV, BitDepth: 16}, ADPCMSettings{Encoding: IMA_ADPCM}},
+		{"adpcm signed", AudioFormat{Type: TYPE_WAV}, ADPCMSettings{Encoding: SIGNED_INTEGER}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.settings.validateFor(&tc.output)
+			assert.ErrorIs(t, err, ErrInvalidFormat)
+
+			var formatErr *FormatError
+			require.ErrorAs(t, err, &formatErr)
+			assert.Equal(t, "Encoder", formatErr.Field)
+		})
+	}
+}
+
+// TestEncoder_ADPCMExample verifies the ADPCMSettings doc example builds a valid Task
+func TestEncoder_ADPCMExample(t *testing.T) {
+	input := PCM_RAW_8K_MONO
+	task := New(input, AudioFormat{Type: TYPE_WAV, SampleRate: 16000, Channels: 1}).
+		WithEncoder(ADPCMSettings{Encoding: IMA_ADPCM})
+	require.NoError(t, task.validateFormats())
+
+	args := strings.Join(task.buildArgs("-", "out.wav"), " ")
+	assert.True(t, strings.HasSuffix(args, "-t wav -c 1 -r 16000 -e ima-adpcm -b 4 out.wav"), args)
+
+	// A 16-bit signed output leaves no room for the ADPCM encoding
+	task = New(input, WAV_16K_MONO_LE).WithEncoder(ADPCMSettings{Encoding: IMA_ADPCM})
+	assert.ErrorIs(t, task.validateFormats(), ErrInvalidFormat)
+}
+
+// TestEncoder_TaskArgs verifies encoder options are output-scoped and -q is only the quiet flag
+func TestEncoder_TaskArgs(t *testing.T) {
+	task := New(PCM_RAW_8K_MONO, FLAC_16K_MONO_LE).WithEncoder(FLACSettings{Level: 8})
+	args := task.buildArgs("-", "out.flac")
+	assert.Equal(t, []string{"-C", "8", "out.flac"}, args[len(args)-3:], "-C belongs to the output")
+
+	// Deprecated options map to the output encoder
+	opts := DefaultOptions()
+	opts.CompressionLevel = 3
+	opts.Quality = 7
+	task = New(PCM_RAW_8K_MONO, FLAC_16K_MONO_LE).WithOptions(opts)
+	args = task.buildArgs("-", "out.flac")
+	assert.Equal(t, []string{"-C", "3", "out.flac"}, args[len(args)-3:])
+	assert.Equal(t, 1, strings.Count(strings.Join(args, " "), "-q"), "only the quiet flag")
+	assert.NotContains(t, args, "7")
+
+	task = New(PCM_RAW_8K_MONO, AudioFormat{Type: TYPE_OGG}).WithOptions(opts)
+	args = task.buildArgs("-", "out.ogg")
+	assert.Equal(t, []string{"-C", "7", "out.ogg"}, args[len(args)-3:])
+
+	// Settings are validated against the output before sox runs
+	task = New(PCM_RAW_8K_MONO, WAV_16K_MONO).WithEncoder(MP3Settings{Bitrate: 128})
+	err := task.Convert(bytes.NewReader(generatePCMData(8000, 100)), &bytes.Buffer{})
+	assert.ErrorIs(t, err, ErrInvalidFormat)
+	assert.Contains(t, err.Error(), "Encoder")
+
+	output := FLAC_16K_MONO_LE
+	output.Compression = 5
+	task = New(PCM_RAW_8K_MONO, output).WithEncoder(FLACSettings{Level: 8})
+	err = task.Convert(bytes.NewReader(generatePCMData(8000, 100)), &bytes.Buffer{})
+	assert.ErrorIs(t, err, ErrInvalidFormat)
+	assert.Contains(t, err.Error(), "Compression")
+}
+
+// TEST SUITE 17: Preset Registry
+// ═══════════════════════════════════════════════════════════
+
+// TestPresetRegistry_Lookup verifies built-in presets are listed and looked up by name
+func TestPresetRegistry_Lookup(t *testing.T) {
+	names := Presets()
+	assert.Contains(t, names, "ulaw_8k_mono")
+	assert.Contains(t, names, "alaw_8k_mono")
+	assert.Contains(t, names, "wav_44k_stereo")
+	assert.IsIncreasing(t, names)
+
+	for _, name := range []string{"ULAW_8K_MONO", "ulaw-8k-mono", " ulaw_8k_mono "} {
+		format, ok := Preset(name)
+		assert.True(t, ok, name)
+		assert.Equal(t, ULAW_8K_MONO, format)
+	}
+
+	_, ok := Preset("no_such_preset")
+	assert.False(t, ok)
+
+	format, err := ParseFormat("L16_16K_MONO")
+	require.NoError(t, err)
+	assert.Equal(t, L16_16K_MONO, format)
+	assert.Equal(t, "raw:s16be@16000/1", format.String())
+}
+
+// TestPresetRegistry_Register verifies custom presets are registered, validated and copied
+func TestPresetRegistry_Register(t *testing.T) {
+	tmpDir := t.TempDir()
+	custom := AudioFormat{
+		Type:       TYPE_FLAC,
+		SampleRate: 8000,
+		Channels:   1,
+		BitDepth:   16,
+		CustomArgs: []string{"--add-comment", "archive"},
+	}
+
+	name := "test-archive-" + filepath.Base(tmpDir)
+	require.NoError(t, RegisterPreset(name, custom))
+	assert.Contains(t, Presets(), normalizePresetName(name))
+
+	custom.CustomArgs[1] = "changed"
+	format, ok := Preset(strings.ToUpper(name))
+	require.True(t, ok)
+	assert.Equal(t, "archive", format.CustomArgs[1], "registry keeps its own copy")
+
+	format.CustomArgs[1] = "changed"
+	format, _ = Preset(name)
+	assert.Equal(t, "archive", format.CustomArgs[1], "lookups return copies")
+
+	assert.Error(t, RegisterPreset(name, custom), "names cannot be registered twice")
+	assert.Error(t, RegisterPreset("ulaw_8k_mono", custom), "built-ins cannot be replaced")
+	assert.Error(t, RegisterPreset(" ", custom))
+
+	err := RegisterPreset(name+"-invalid", AudioFormat{Type: TYPE_FLAC, Encoding: UNSIGNED_INTEGER})
+	assert.ErrorIs(t, err, ErrInvalidFormat)
+}
+
+// TEST SUITE 18: Audio Math and Frame Alignment
+// ═══════════════════════════════════════════════════════════
+
+// TestAudioMath_Sizes verifies frame sizes, byte rates and their conversion to durations
+func TestAudioMath_Sizes(t *testing.T) {
+	assert.Equal(t, 2, PCM_RAW_16K_MONO.FrameSize())
+	assert.Equal(t, 32000, PCM_RAW_16K_MONO.BytesPerSecond())
+	assert.Equal(t, 6, FLAC_48K_STEREO.FrameSize())
+	assert.Equal(t, 8000, ULAW_8K_MONO.BytesPerSecond())
+
+	assert.Equal(t, 20*time.Millisecond, PCM_RAW_16K_MONO.Duration(640))
+	assert.Equal(t, time.Hour, PCM_RAW_16K_MONO.Duration(32000*3600))
+	assert.Equal(t, 640, PCM_RAW_16K_MONO.BytesFor(20*time.Millisecond))
+	assert.Equal(t, 160, ULAW_8K_MONO.BytesFor(20*time.Millisecond))
+
+	// Partial frames are rounded down
+	assert.Equal(t, 2, PCM_RAW_16K_MONO.BytesFor(90*time.Microsecond))
+	assert.Equal(t, 1920, WAV_48K_STEREO.BytesFor(10*time.Millisecond))
+
+	// Unknown byte rates and negative durations give zero
+	mp3 := AudioFormat{Type: TYPE_MP3}
+	assert.Equal(t, 0, mp3.FrameSize())
+	assert.Equal(t, time.Duration(0), mp3.Duration(4096))
+	assert.Equal(t, 0, mp3.BytesFor(time.Second))
+	assert.Equal(t, 0, PCM_RAW_16K_MONO.BytesFor(-time.Second))
+}
+
+// TestFrameAlignment_TickerWrite verifies partial frames are held back until completed
+func TestFrameAlignment_TickerWrite(t *testing.T) {
+	task := New(PCM_RAW_8K_MONO, FLAC_16K_MONO_LE).
+		WithTicker(time.Hour).
+		WithFrameAlignment()
+	require.NoError(t, task.Start())
+
+	n, err := task.Write([]byte{1, 2, 3})
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+	assert.Equal(t, []byte{1, 2}, task.tickerBuffer.Bytes())
+
+	n, err = task.Write([]byte{4, 5})
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, []byte{1, 2, 3, 4}, task.tickerBuffer.Bytes())
+	assert.Equal(t, []byte{5}, task.writeCarry)
+
+	require.NoError(t, task.Stop())
+
+	// A restart drops the held back byte
+	require.NoError(t, task.Start())
+	assert.Empty(t, task.writeCarry)
+	require.NoError(t, task.Stop())
+}
+
+// TestFrameAlignment_RequiresFrameInfo verifies Start fails when frames cannot be sized
+func TestFrameAlignment_RequiresFrameInfo(t *testing.T) {
+	task := New(AudioFormat{Type: TYPE_WAV}, FLAC_16K_MONO_LE).
+		WithTicker(time.Hour).
+		WithFrameAlignment()
+
+	err := task.Start()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "frame alignment")
+	assert.Equal(t, TaskIdle, task.State())
+}
+
+// TEST SUITE 19: MIME Types
+// ═══════════════════════════════════════════════════════════
+
+// TestMIME_ContentType verifies content types and extensions of formats
+func TestMIME_ContentType(t *testing.T) {
+	tests := []struct {
+		format      AudioFormat
+		contentType string
+		extension   string
+	}{
+		{FLAC_16K_MONO, "audio/flac", ".flac"},
+		{WAV_16K_MONO, "audio/wav", ".wav"},
+		{MP3_VOICE, "audio/mpeg", ".mp3"},
+		{OGG_VOICE, "audio/ogg", ".ogg"},
+		{AudioFormat{Type: TYPE_OPUS}, "audio/ogg;codecs=opus", ".opus"},
+		{GSM_8K_MONO, "audio/GSM", ".gsm"},
+		{ULAW_8K_MONO, "audio/basic", ".ul"},
+		{ALAW_8K_MONO, "audio/PCMA;rate=8000;channels=1", ".al"},
+		{L16_16K_MONO, "audio/L16;rate=16000;channels=1", ".raw"},
+		{L16_48K_STEREO, "audio/L16;rate=48000;channels=2", ".raw"},
+		{AudioFormat{Type: TYPE_RAW, Encoding: "ulaw", SampleRate: 16000, Channels: 1}, "audio/PCMU;rate=16000;channels=1", ".ul"},
+		{AudioFormat{Type: TYPE_ALAW}, "audio/PCMA", ".al"},
+
+		// Little-endian PCM has no MIME type of its own
+		{PCM_RAW_16K_MONO, "application/octet-stream", ".raw"},
+		{AudioFormat{Type: "voc"}, "application/octet-stream", ".voc"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.contentType, tt.format.ContentType(), tt.format.String())
+		assert.Equal(t, tt.extension, tt.format.Extension(), tt.format.String())
+	}
+}
+
+// TestMIME_FormatFromContentType verifies MIME types and their parameters are parsed
+func TestMIME_FormatFromContentType(t *testing.T) {
+	tests := []struct {
+		contentType string
+		expected    AudioFormat
+	}{
+		{"audio/flac", AudioFormat{Type: TYPE_FLAC}},
+		{"audio/x-wav", AudioFormat{Type: TYPE_WAV}},
+		{"audio/mpeg", AudioFormat{Type: TYPE_MP3}},
+		{"audio/ogg; codecs=opus", AudioFormat{Type: TYPE_OPUS}},
+		{"audio/ogg", AudioFormat{Type: TYPE_OGG}},
+		{"audio/basic", ULAW_8K_MONO},
+		{"audio/L16;rate=16000;channels=1", L16_16K_MONO},
+		{"audio/l16; rate=48000; channels=2", L16_48K_STEREO},
+		{"audio/PCMA", ALAW_8K_MONO},
+		{"audio/GSM", GSM_8K_MONO},
+	}
+
+	for _, tt := range tests {
+		f, err := FormatFromContentType(tt.contentType)
+		require.NoError(t, err, tt.contentType)
+		assert.Equal(t, tt.expected.String(), f.String(), tt.contentType)
+	}
+
+	// Raw formats with a MIME type survive a round trip
+	for _, format := range []AudioFormat{ULAW_8K_MONO, ALAW_8K_MONO, L16_16K_MONO, L16_48K_STEREO} {
+		f, err := FormatFromContentType(format.ContentType())
+		require.NoError(t, err)
+		assert.Equal(t, format.String(), f.String())
+	}
+
+	_, err := FormatFromContentType("video/mp4")
+	assert.ErrorIs(t, err, ErrUnsupportedContentType)
+
+	_, err = FormatFromContentType("audio/L16")
+	assert.ErrorContains(t, err, "requires a rate")
+
+	_, err = FormatFromContentType("audio/L16;rate=abc")
+	assert.ErrorContains(t, err, "bad rate")
+
+	_, err = FormatFromContentType("audio/G729")
+	assert.ErrorIs(t, err, ErrUnsupportedContentType)
+
+	_, err = FormatFromContentType("audio/")
+	assert.Error(t, err)
+}
+
+// TestMIME_FormatFromAccept verifies Accept headers select the preferred supported type
+func TestMIME_FormatFromAccept(t *testing.T) {
+	f, err := FormatFromAccept("audio/webm;codecs=opus, audio/ogg;q=0.8, */*;q=0.1")
+	require.NoError(t, err)
+	assert.Equal(t, TYPE_OGG, f.Type)
+
+	f, err = FormatFromAccept(`audio/wav;q=0.5, audio/ogg; codecs="opus, vorbis"; q=0.9, audio/flac;q=0`)
+	require.NoError(t, err)
+	assert.Equal(t, TYPE_OPUS, f.Type)
+
+	f, err = FormatFromAccept("audio/mpeg, audio/wav")
+	require.NoError(t, err)
+	assert.Equal(t, TYPE_MP3, f.Type, "header order breaks ties")
+
+	f, err = FormatFromAccept("audio/L16;rate=8000")
+	require.NoError(t, err)
+	assert.Equal(t, 8000, f.SampleRate)
+
+	_, err = FormatFromAccept("audio/webm, audio/*, */*")
+	assert.ErrorIs(t, err, ErrUnsupportedContentType)
+
+	// A single Content-Type only
+	_, err = FormatFromContentType("audio/wav, audio/flac")
+	assert.Error(t, err)
+}
+
+// TEST SUITE 20: Probing
+// ═══════════════════════════════════════════════════════════
+
+// fakeSox writes a shell script standing in for sox 14.4.2 and returns its path
+func fakeSox(t *testing.T, script string) string {
+	path := filepath.Join(t.TempDir(), "fake-sox")
+	version := "if [ \"$1\" = --version ]; then echo \"sox:      SoX v14.4.2\"; exit 0; fi\n"
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\n"+version+script), 0755))
+
+	return path
+}
+
+// soxiWAV is the sox --i summary of a 16 kHz mono 16-bit WAV file with comments
+const soxiWAV = `
+Input File     : 'call.wav'
+Channels       : 1
+Sample Rate    : 16000
+Precision      : 16-bit
+Duration       : 00:00:02.50 = 40.0k samples ~ 187.5 CDDA sectors
+File Size      : 80.0k
+Bit Rate       : 256k
+Sample Encoding: 16-bit Signed Integer PCM
+Comments       : 
+Title=Greeting
+Artist=IVR
+`
+
+// TestProbe_ParseSummary verifies the sox --i summary is parsed
+func TestProbe_ParseSummary(t *testing.T) {
+	info := parseSoxInfo([]byte(soxiWAV))
+	assert.Equal(t, 1, info.Channels)
+	assert.Equal(t, 16000, info.SampleRate)
+	assert.Equal(t, 16, info.Precision)
+	assert.Equal(t, 16, info.BitDepth)
+	assert.Equal(t, SIGNED_INTEGER, info.Encoding)
+	assert.Equal(t, 2500*time.Millisecond, info.Duration)
+	assert.Zero(t, info.Samples, "abbreviated sample count")
+	assert.Equal(t, []string{"Title=Greeting", "Artist=IVR"}, info.Comments)
+
+	info = parseSoxInfo([]byte(`
+Input File     : 'song.mp3'
+Channels       : 2
+Sample Rate    : 44100
+Precision      : 16-bit
+Duration       : 00:00:00.02 = 800 samples ~ 1.36054 CDDA sectors
+Sample Encoding: MPEG audio (layer I, II or III)
+Comment        : 'Title=Song: Live'
+`))
+	assert.Equal(t, 2, info.Channels)
+	assert.Equal(t, 0, info.BitDepth)
+	assert.Equal(t, Encoding(""), info.Encoding)
+	assert.Equal(t, 20*time.Millisecond, info.Duration)
+	assert.Equal(t, int64(800), info.Samples)
+	assert.Equal(t, []string{"Title=Song: Live"}, info.Comments)
+
+	assert.Equal(t, time.Hour+2*time.Minute+3450*time.Millisecond, parseClock("01:02:03.45"))
+
+	bits, enc := parseSampleEncoding("8-bit u-law")
+	assert.Equal(t, 8, bits)
+	assert.Equal(t, MU_LAW, enc)
+
+	// FLAC reports its precision, not a stored sample size
+	info = parseSoxInfo([]byte(`
+Input File     : 'call.flac'
+Channels       : 1
+Sample Rate    : 16000
+Precision      : 16-bit
+Sample Encoding: 16-bit FLAC
+`))
+	info.Type = TYPE_FLAC
+	assert.Equal(t, 16, info.Precision)
+	assert.Equal(t, 0, info.BitDepth)
+	assert.Equal(t, Encoding(""), info.Encoding)
+	format := info.Format()
+	assert.NoError(t, format.ValidateFor(RoleInput))
+}
+
+// TestProbe_Task verifies Probe runs sox once for paths and readers
+func TestProbe_Task(t *testing.T) {
+	tmpDir := t.TempDir()
+	summary := filepath.Join(tmpDir, "summary.txt")
+	require.NoError(t, os.WriteFile(summary, []byte(soxiWAV), 0644))
+	runs := filepath.Join(tmpDir, "runs.txt")
+
+	opts := DefaultOptions()
+	opts.SoxPath = fakeSox(t, `echo "$@" >> "`+runs+`"
+case "$2" in
+-s) echo 40001 ;;
+*) cat "`+summary+`" ;;
+esac
+`)
+	task := New().WithOptions(opts)
+
+	wav := buildWAV(generatePCMData(16000, 2500), 16000, 1, 16)
+	path := filepath.Join(tmpDir, "call.wav")
+	require.NoError(t, os.WriteFile(path, wav, 0644))
+
+	for _, input := range []interface{}{path, bytes.NewReader(wav)} {
+		info, err := task.Probe(context.Background(), input)
+		require.NoError(t, err)
+
+		assert.Equal(t, TYPE_WAV, info.Type)
+		assert.Equal(t, int64(40000), info.Samples)
+		assert.Equal(t, 2500*time.Millisecond, info.Duration)
+		assert.Equal(t, int64(len(wav)), info.Size)
+		assert.Equal(t, len(wav)*8*2/5, info.Bitrate)
+		assert.Equal(t, "wav:s16@16000/1", info.Format().String())
+	}
+
+	log, err := os.ReadFile(runs)
+	require.NoError(t, err)
+	assert.Equal(t, 2, strings.Count(string(log), "\n"), "one sox run per probe")
+
+	samples, err := task.ProbeSamples(context.Background(), path)
+	require.NoError(t, err)
+	assert.Equal(t, int64(40001), samples)
+
+	_, err = task.Probe(context.Background(), 42)
+	assert.Error(t, err)
+}
+
+// TestProbe_Resilience verifies Probe honors the timeout and the circuit breaker
+func TestProbe_Resilience(t *testing.T) {
+	opts := DefaultOptions()
+	opts.SoxPath = fakeSox(t, "sleep 5\n")
+	opts.Timeout = 50 * time.Millisecond
+
+	task := New().WithOptions(opts).WithCircuitBreaker(NewCircuitBreakerWithConfig(1, time.Hour, 1))
+
+	_, err := task.Probe(context.Background(), "call.wav")
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	_, err = task.Probe(context.Background(), "call.wav")
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+}
+
+// TEST SUITE 21: Signal Statistics
+// ═══════════════════════════════════════════════════════════
+
+// statsStereo is the stats report of a clipped stereo recording
+const statsStereo = `             Overall     Left      Right
+DC offset   0.000013  0.000013  0.000010
+Min level  -1.000000 -1.000000 -0.501953
+Max level   0.999969  0.999969  0.500000
+Pk lev dB       0.00      0.00     -6.00
+RMS lev dB    -10.21     -7.20    -13.22
+RMS Pk dB      -6.92     -6.92    -12.08
+RMS Tr dB       -inf     -8.01      -inf
+Crest factor       -      2.29      2.29
+Flat factor    12.40     12.40      0.00
+Pk count       1.23k     1.23k         2
+Bit-depth      16/16     16/16     15/16
+Num samples    48.0k
+Length s       1.000
+Scale max   1.000000
+Window s       0.050
+`
+
+// TestStats_Parse verifies mono and multi-channel stats reports are parsed
+func TestStats_Parse(t *testing.T) {
+	stats, err := parseStats([]byte(statsStereo))
+	require.NoError(t, err)
+
+	require.Len(t, stats.Channels, 2)
+	assert.Equal(t, time.Second, stats.Length)
+	assert.Equal(t, 50*time.Millisecond, stats.Window)
+	assert.Equal(t, 1.0, stats.ScaleMax)
+
+	assert.Equal(t, -10.21, stats.Overall.RMSLevel)
+	assert.True(t, math.IsNaN(stats.Overall.CrestFactor))
+	assert.True(t, math.IsInf(stats.Overall.RMSTrough, -1))
+	assert.Equal(t, int64(1230), stats.Overall.Clipped)
+
+	left, right := stats.Channels[0], stats.Channels[1]
+	assert.Equal(t, -1.0, left.MinLevel)
+	assert.Equal(t, 2.29, left.CrestFactor)
+	assert.Equal(t, 12.40, left.FlatFactor)
+	assert.Equal(t, int64(1230), left.Clipped)
+	assert.Equal(t, 16, left.BitsUsed)
+
+	assert.Equal(t, -6.0, right.PeakLevel)
+	assert.Equal(t, int64(2), right.PeakCount)
+	assert.Equal(t, int64(0), right.Clipped)
+	assert.Equal(t, 15, right.BitsUsed)
+	assert.Equal(t, 16, right.BitsTotal)
+
+	// Mono reports have a single column, and warnings are skipped
+	stats, err = parseStats([]byte("sox WARN wav: header length mismatch\nDC offset   0.000100\nPk lev dB      -3.10\nCrest factor    4.00\nPk count           8\n"))
+	require.NoError(t, err)
+	require.Len(t, stats.Channels, 1)
+	assert.Equal(t, stats.Overall, stats.Channels[0])
+	assert.Equal(t, 4.0, stats.Overall.CrestFactor)
+	assert.Equal(t, int64(0), stats.Overall.Clipped)
+
+	_, err = parseStats([]byte("sox FAIL formats: can't open input file"))
+	assert.Error(t, err)
+}
+
+// TestStats_Analyze verifies Analyze runs the stats effect on paths and readers
+func TestStats_Analyze(t *testing.T) {
+	tmpDir := t.TempDir()
+	report := filepath.Join(tmpDir, "stats.txt")
+	argsFile := filepath.Join(tmpDir, "args.txt")
+	require.NoError(t, os.WriteFile(report, []byte(statsStereo), 0644))
+
+	opts := DefaultOptions()
+	opts.SoxPath = fakeSox(t, `echo "$@" > "`+argsFile+`"
+cat > /dev/null
+cat "`+report+`" >&2
+`)
+
+	task := New(PCM_RAW_8K_MONO, PCM_RAW_8K_MONO).WithOptions(opts)
+
+	stats, err := task.Analyze(context.Background(), bytes.NewReader(generatePCMData(8000, 100)))
+	require.NoError(t, err)
+	assert.Len(t, stats.Channels, 2)
+
+	args, err := os.ReadFile(argsFile)
+	require.NoError(t, err)
+	assert.Equal(t, "-q -t raw -e signed-integer -b 16 -c 1 -r 8000 - -n stats\n", string(args))
+
+	_, err = Analyze(context.Background(), "call.wav", AudioFormat{Type: TYPE_FLAC, Encoding: UNSIGNED_INTEGER})
+	assert.ErrorIs(t, err, ErrInvalidFormat)
+}
+
+// TEST SUITE 22: SoX Capabilities
+// ═══════════════════════════════════════════════════════════
+
+// soxHelp is an excerpt of sox -h from a build without mp3 and ladspa
+const soxHelp = `sox:      SoX v14.4.2
+
+Usage summary: [gopts] [[fopts] infile]... [fopts] outfile [effect [effopt]]...
+
+AUDIO FILE FORMATS: 8svx aif aifc aiff al au flac gsm ogg raw sln ul vorbis wav
+PLAYLIST FORMATS: m3u pls
+AUDIO DEVICE DRIVERS: alsa
+
+EFFECTS: allpass band bass channels divide+ fade gain highpass input# norm rate remix silence stats trim vol
+    * Deprecated or experimental effect    + Experimental effect    # LibSoX-only effect
+`
+
+// fakeSoxWithHelp returns a fake sox printing soxHelp for -h and --version
+func fakeSoxWithHelp(t *testing.T) string {
+	help := filepath.Join(t.TempDir(), "help.txt")
+	require.NoError(t, os.WriteFile(help, []byte(soxHelp), 0644))
+
+	return fakeSox(t, `[ "$1" = -h ] && cat "`+help+`" && exit 1
+`)
+}
+
+// TestCapabilities_Parse verifies version, formats and effects are parsed
+func TestCapabilities_Parse(t *testing.T) {
+	caps := parseCapabilities("sox:      SoX v14.4.2-debian", soxHelp)
+
+	assert.Equal(t, "14.4.2", caps.Version)
+	assert.True(t, caps.SupportsFormat("flac"))
+	assert.True(t, caps.SupportsFormat("WAV"))
+	assert.False(t, caps.SupportsFormat("mp3"))
+	assert.False(t, caps.SupportsFormat("m3u"), "playlist formats are not audio formats")
+
+	assert.True(t, caps.SupportsEffect("divide"), "markers are stripped")
+	assert.True(t, caps.SupportsEffect("input"))
+	assert.False(t, caps.SupportsEffect("loudness"))
+	assert.Contains(t, caps.Effects, "norm")
+	assert.True(t, sort.StringsAreSorted(caps.Formats))
+}
+
+// TestCapabilities_ProbeSoxCaches verifies ProbeSox reads the binary once
+func TestCapabilities_ProbeSoxCaches(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := fakeSoxWithHelp(t)
+
+	caps, err := ProbeSox(path)
+	require.NoError(t, err)
+	assert.Equal(t, path, caps.Path)
+	assert.True(t, caps.SupportsFormat("ogg"))
+
+	// The cached result survives the binary going away
+	require.NoError(t, os.Remove(path))
+	cached, err := ProbeSox(path)
+	require.NoError(t, err)
+	assert.Same(t, caps, cached)
+
+	_, err = ProbeSox(filepath.Join(tmpDir, "missing-sox"))
+	assert.Error(t, err)
+}
+
+// TestCapabilities_Preflight verifies types and effects are checked against sox
+func TestCapabilities_Preflight(t *testing.T) {
+	tmpDir := t.TempDir()
+	opts := DefaultOptions()
+	opts.SoxPath = fakeSoxWithHelp(t)
+	opts.Effects = []string{"highpass", "100", "norm", "-3", "rate", "-v", "16k"}
+
+	task := New(PCM_RAW_8K_MONO, FLAC_16K_MONO).WithOptions(opts)
+	assert.NoError(t, task.Preflight())
+
+	opts.Effects = []string{"highpass", "100", "loudness", "-10"}
+	task = New(AudioFormat{Type: TYPE_MP3}, AudioFormat{}).
+		WithOptions(opts).
+		WithOutputPath(filepath.Join(tmpDir, "out.m4a"))
+
+	err := task.Preflight()
+	require.ErrorIs(t, err, ErrNotSupported)
+	assert.Contains(t, err.Error(), `input type "mp3"`)
+	assert.Contains(t, err.Error(), `output type "m4a"`)
+	assert.Contains(t, err.Error(), `effect "loudness"`)
+	assert.NotContains(t, err.Error(), "highpass")
+
+	// A misspelled leading effect is caught too
+	opts.Effects = []string{"nrom"}
+	err = New(PCM_RAW_8K_MONO, FLAC_16K_MONO).WithOptions(opts).Preflight()
+	assert.ErrorIs(t, err, ErrNotSupported)
+}
+
+// TEST SUITE 23: SoX Discovery
+// ═══════════════════════════════════════════════════════════
+
+// TestFindSox_Explicit verifies an explicit path is resolved to an absolute path
+func TestFindSox_Explicit(t *testing.T) {
+	path := fakeSox(t, "")
+
+	found, err := FindSox(path)
+	require.NoError(t, err)
+	assert.Equal(t, path, found)
+	assert.NoError(t, CheckSoxInstalled(path))
+}
+
+// TestFindSox_Env verifies SOX_PATH is used when no path is configured
+func TestFindSox_Env(t *testing.T) {
+	path := fakeSox(t, "")
+	t.Setenv("SOX_PATH", path)
+	RefreshSox()
+	defer RefreshSox()
+
+	found, err := FindSox("")
+	require.NoError(t, err)
+	assert.Equal(t, path, found)
+}
+
+// TestFindSox_NotFound verifies missing binaries are reported with ErrSoxNotFound
+func TestFindSox_NotFound(t *testing.T) {
+	tmpDir := t.TempDir()
+	_, err := FindSox(filepath.Join(tmpDir, "missing-sox"))
+	assert.ErrorIs(t, err, ErrSoxNotFound)
+
+	t.Setenv("SOX_PATH", filepath.Join(tmpDir, "missing-sox"))
+	RefreshSox()
+	defer RefreshSox()
+
+	_, err = FindSox("")
+	assert.ErrorIs(t, err, ErrSoxNotFound)
+	assert.Contains(t, err.Error(), "SOX_PATH")
+}
+
+// TestFindSox_TooOld verifies sox releases older than MinSoxVersion are rejected
+func TestFindSox_TooOld(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "old-sox")
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\necho \"sox: SoX v14.3.2\"\n"), 0755))
+
+	_, err := FindSox(path)
+	var versionErr *VersionError
+	require.ErrorAs(t, err, &versionErr)
+	assert.Equal(t, "14.3.2", versionErr.Version)
+	assert.Equal(t, MinSoxVersion, versionErr.Minimum)
+	assert.Equal(t, path, versionErr.Path)
+
+	// Conversions fail before running the binary
+	opts := DefaultOptions()
+	opts.SoxPath = path
+	task := New(PCM_RAW_8K_MONO, PCM_RAW_8K_MONO).WithOptions(opts)
+	assert.ErrorAs(t, task.Convert(bytes.NewReader(generatePCMData(8000, 10)), io.Discard), &versionErr)
+}
+
+// TestFindSox_Cache verifies results are cached until RefreshSox
+func TestFindSox_Cache(t *testing.T) {
+	path := fakeSox(t, "")
+
+	_, err := FindSox(path)
+	require.NoError(t, err)
+
+	require.NoError(t, os.Remove(path))
+	_, err = FindSox(path)
+	assert.NoError(t, err, "cached result should be used")
+
+	RefreshSox()
+	_, err = FindSox(path)
+	assert.ErrorIs(t, err, ErrSoxNotFound)
+}
+
+// TestFindSox_NotExecutable verifies a binary that fails sox --version is reported
+// with ErrSoxNotFound
+func TestFindSox_NotExecutable(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "broken-sox")
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\nexit 1\n"), 0755))
+
+	_, err := FindSox(path)
+	assert.ErrorIs(t, err, ErrSoxNotFound)
+	assert.ErrorIs(t, CheckSoxInstalled(path), ErrSoxNotFound)
+}
+
+// TestFindSox_SlowBinary verifies a slow sox does not hold up discovery of another
+func TestFindSox_SlowBinary(t *testing.T) {
+	tmpDir := t.TempDir()
+	slow := filepath.Join(tmpDir, "slow-sox")
+	require.NoError(t, os.WriteFile(slow, []byte("#!/bin/sh\nexec sleep 2\n"), 0755))
+	defer RefreshSox()
+
+	go FindSox(slow)
+	time.Sleep(50 * time.Millisecond)
+
+	start := time.Now()
+	_, err := FindSox(fakeSox(t, ""))
+	require.NoError(t, err)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+// TestCompareVersions verifies dotted versions are compared numerically
+func TestCompareVersions(t *testing.T) {
+	assert.Equal(t, 0, compareVersions("14.4", "14.4.0"))
+	assert.Equal(t, 1, compareVersions("14.4.2", "14.4"))
+	assert.Equal(t, -1, compareVersions("14.3.2", "14.4"))
+	assert.Equal(t, 1, compareVersions("14.10", "14.4"))
+	assert.Equal(t, 1, compareVersions("15", "14.4.2"))
+}
+
+// TEST SUITE 24: Segment Detection
+// ═══════════════════════════════════════════════════════════
+
+// floatPCM encodes interleaved samples as 32-bit float little-endian PCM
+func floatPCM(samples []float32) []byte {
+	data := make([]byte, 4*len(samples))
+	for i, v := range samples {
+		binary.LittleEndian.PutUint32(data[4*i:], math.Float32bits(v))
+	}
+
+	return data
+}
+
+// speechPattern returns mono samples at 1 kHz: 500ms silence, a 50ms click, 500ms
+// silence, 400ms tone, a 100ms pause, 400ms tone and 300ms silence
+func speechPattern() []float32 {
+	var samples []float32
+	add := func(ms int, level float32) {
+		for i := 0; i < ms; i++ {
+			if i%2 == 1 {
+				samples = append(samples, -level)
+			} else {
+				samples = append(samples, level)
+			}
+		}
+	}
+
+	add(500, 0)
+	add(50, 0.5)
+	add(500, 0.001)
+	add(400, 0.5)
+	add(100, 0)
+	add(400, 0.5)
+	add(300, 0)
+
+	return samples
+}
+
+// TestSegments_Runs verifies windows are classified and minimum durations applied
+func TestSegments_Runs(t *testing.T) {
+	detector := newSegmentDetector(1, 10)
+
+	// Feed in uneven chunks, as decoding does
+	samples := speechPattern()
+	detector.add(samples[:333])
+	detector.add(samples[333:])
+	detector.finish()
+
+	runs := detector.runs(0, DefaultSilenceConfig(), 1000)
+	require.Len(t, runs, 3)
+
+	assert.False(t, runs[0].speech, "the click is shorter than MinSpeech")
+	assert.Equal(t, int64(1050), runs[0].end)
+	assert.True(t, runs[1].speech)
+	assert.Equal(t, int64(1050), runs[1].start)
+	assert.Equal(t, int64(1950), runs[1].end, "the pause is shorter than MinSilence")
+	assert.False(t, runs[2].speech)
+	assert.Equal(t, int64(2250), runs[2].end)
+
+	// Without minimum durations every change is a segment
+	runs = detector.runs(0, SilenceConfig{Threshold: -40}, 1000)
+	assert.Len(t, runs, 7)
+}
+
+// TestSegments_Detect verifies sox decodes to float PCM and segments are timed
+func TestSegments_Detect(t *testing.T) {
+	tmpDir := t.TempDir()
+	decoded := filepath.Join(tmpDir, "decoded.f32")
+	argsFile := filepath.Join(tmpDir, "args.txt")
+	require.NoError(t, os.WriteFile(decoded, floatPCM(speechPattern()), 0644))
+
+	opts := DefaultOptions()
+	opts.SoxPath = fakeSox(t, `echo "$@" > "`+argsFile+`"
+cat > /dev/null
+cat "`+decoded+`"
+`)
+
+	format := PCM_RAW_8K_MONO
+	format.SampleRate = 1000
+	task := New(format, format).WithOptions(opts)
+
+	config := DefaultSilenceConfig()
+	config.Window = 10 * time.Millisecond
+
+	segments, err := task.DetectSegments(context.Background(), bytes.NewReader(make([]byte, 100)), config)
+	require.NoError(t, err)
+	require.Len(t, segments, 3)
+
+	assert.False(t, segments[0].Speech)
+	assert.Less(t, segments[0].Level, segments[1].Level)
+	assert.Equal(t, 1050*time.Millisecond, segments[1].Start)
+	assert.Equal(t, 1950*time.Millisecond, segments[1].End)
+	assert.Equal(t, 900*time.Millisecond, segments[1].Duration())
+	assert.InDelta(t, -6.6, segments[1].Level, 0.1)
+	assert.Equal(t, 0, segments[1].Channel)
+	assert.Equal(t, 2250*time.Millisecond, segments[2].End)
+
+	args, err := os.ReadFile(argsFile)
+	require.NoError(t, err)
+	assert.Contains(t, string(args), "- -t raw -e floating-point -b 32 --endian little -c 1 -r 1000 -")
+}
+
+// TestSegments_PerChannel verifies each channel is segmented on its own
+func TestSegments_PerChannel(t *testing.T) {
+	tmpDir := t.TempDir()
+	pattern := speechPattern()
+	stereo := make([]float32, 0, 2*len(pattern))
+	for _, v := range pattern {
+		stereo = append(stereo, v, 0)
+	}
+
+	decoded := filepath.Join(tmpDir, "decoded.f32")
+	require.NoError(t, os.WriteFile(decoded, floatPCM(stereo), 0644))
+
+	opts := DefaultOptions()
+	opts.SoxPath = fakeSox(t, `cat > /dev/null
+cat "`+decoded+`"
+`)
+
+	format := AudioFormat{Type: TYPE_RAW, Encoding: SIGNED_INTEGER, BitDepth: 16, SampleRate: 1000}
+	task := New(format, format).WithOptions(opts)
+
+	config := DefaultSilenceConfig()
+	config.Window = 10 * time.Millisecond
+	config.PerChannel = true
+
+	_, err := task.DetectSegments(context.Background(), bytes.NewReader(nil), config)
+	assert.ErrorContains(t, err, "requires Input.Channels")
+
+	task.Input.Channels = 2
+	segments, err := task.DetectSegments(context.Background(), bytes.NewReader(nil), config)
+	require.NoError(t, err)
+	require.Len(t, segments, 4)
+
+	assert.Equal(t, 1, segments[0].Channel)
+	assert.True(t, segments[1].Speech)
+	assert.Equal(t, Segment{Start: 0, End: 2250 * time.Millisecond, Channel: 2, Level: math.Inf(-1)}, segments[3])
+}
+
+// TestSegments_Config verifies invalid configurations are rejected
+func TestSegments_Config(t *testing.T) {
+	assert.NoError(t, DefaultSilenceConfig().Validate())
+	assert.Error(t, SilenceConfig{}.Validate(), "a 0 dBFS threshold is never reached")
+	assert.Error(t, SilenceConfig{Threshold: -40, MinSpeech: -time.Second}.Validate())
+
+	_, err := DetectSegments(context.Background(), "call.wav", AudioFormat{}, SilenceConfig{Threshold: 3})
+	assert.ErrorContains(t, err, "threshold")
+}
+
+// TEST SUITE 25: Spectrograms
+// ═══════════════════════════════════════════════════════════
+
+// fakeSpectrogram returns a fake sox recording its arguments and writing a fake
+// image to the -o path
+func fakeSpectrogram(t *testing.T, argsFile string) string {
+	return fakeSox(t, `echo "$@" > "`+argsFile+`"
+cat > /dev/null
+while [ $# -gt 0 ]; do
+	[ "$1" = -o ] && printf 'PNG image' > "$2"
+	shift
+done
+`)
+}
+
+// TestSpectrogram_Render verifies the effect arguments and that the image reaches the writer
+func TestSpectrogram_Render(t *testing.T) {
+	tmpDir := t.TempDir()
+	argsFile := filepath.Join(tmpDir, "args.txt")
+	opts := DefaultOptions()
+	opts.SoxPath = fakeSpectrogram(t, argsFile)
+
+	task := New(PCM_RAW_8K_MONO, PCM_RAW_8K_MONO).WithOptions(opts)
+
+	var image bytes.Buffer
+	err := task.Spectrogram(context.Background(), bytes.NewReader(generatePCMData(8000, 100)), SpectrogramOptions{
+		Width:        1200,
+		Height:       257,
+		DynamicRange: 90,
+		Window:       WINDOW_KAISER,
+		Monochrome:   true,
+		Title:        "ticket 1234",
+		Start:        1500 * time.Millisecond,
+		Duration:     10 * time.Second,
+	}, &image)
+	require.NoError(t, err)
+	assert.Equal(t, "PNG image", image.String())
+
+	args, err := os.ReadFile(argsFile)
+	require.NoError(t, err)
+	assert.Contains(t, string(args),
+		"-r 8000 - -n channels 1 spectrogram -x 1200 -y 257 -z 90 -w Kaiser -m -t ticket 1234 -S 1.5 -d 10 -o ")
+
+	// Per-channel plots skip the mixdown, raw mode drops the axes
+	err = task.Spectrogram(context.Background(), bytes.NewReader(nil), SpectrogramOptions{PerChannel: true, Raw: true}, io.Discard)
+	require.NoError(t, err)
+
+	args, err = os.ReadFile(argsFile)
+	require.NoError(t, err)
+	assert.Contains(t, string(args), "- -n spectrogram -r -o ")
+}
+
+// TestSpectrogram_Failure verifies nothing is written when sox fails
+func TestSpectrogram_Failure(t *testing.T) {
+	opts := DefaultOptions()
+	opts.SoxPath = fakeSox(t, "echo 'sox FAIL spectrogram: bad option' >&2\nexit 1\n")
+
+	var image bytes.Buffer
+	err := New(PCM_RAW_8K_MONO, PCM_RAW_8K_MONO).WithOptions(opts).
+		Spectrogram(context.Background(), bytes.NewReader(nil), SpectrogramOptions{}, &image)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "sox spectrogram failed")
+	assert.Zero(t, image.Len())
+}
+
+// TestSpectrogram_Validate verifies options outside the sox ranges are rejected
+func TestSpectrogram_Validate(t *testing.T) {
+	assert.NoError(t, SpectrogramOptions{}.Validate())
+	assert.Error(t, SpectrogramOptions{Width: 50}.Validate())
+	assert.Error(t, SpectrogramOptions{Height: 2000}.Validate())
+	assert.Error(t, SpectrogramOptions{DynamicRange: 200}.Validate())
+	assert.Error(t, SpectrogramOptions{Window: "Blackman"}.Validate())
+	assert.Error(t, SpectrogramOptions{Start: -time.Second}.Validate())
+
+	err := Spectrogram(context.Background(), "call.wav", AudioFormat{}, SpectrogramOptions{Width: 1}, io.Discard)
+	assert.ErrorContains(t, err, "width")
+}
+
+// TEST SUITE 26: Waveform Peaks
+// ═══════════════════════════════════════════════════════════
+
+// TestPeaks_Compute verifies min/max pairs per block, per channel and merged
+func TestPeaks_Compute(t *testing.T) {
+	tmpDir := t.TempDir()
+	// Stereo at 1 kHz: a 0.5 ramp on the left, silence on the right, 10 frames
+	var stereo []float32
+	for i := 0; i < 10; i++ {
+		stereo = append(stereo, float32(i)/20, 0)
+	}
+
+	decoded := filepath.Join(tmpDir, "decoded.f32")
+	argsFile := filepath.Join(tmpDir, "args.txt")
+	require.NoError(t, os.WriteFile(decoded, floatPCM(stereo), 0644))
+
+	opts := DefaultOptions()
+	opts.SoxPath = fakeSox(t, `echo "$@" > "`+argsFile+`"
+cat > /dev/null
+cat "`+decoded+`"
+`)
+
+	format := AudioFormat{Type: TYPE_RAW, Encoding: SIGNED_INTEGER, BitDepth: 16, SampleRate: 1000, Channels: 2}
+	task := New(format, format).WithOptions(opts)
+
+	waveform, err := task.Peaks(context.Background(), bytes.NewReader(nil), PeaksOptions{SamplesPerPixel: 4, PerChannel: true})
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, waveform.Version)
+	assert.Equal(t, 2, waveform.Channels)
+	assert.Equal(t, 1000, waveform.SampleRate)
+	assert.Equal(t, 16, waveform.Bits)
+	assert.Equal(t, 3, waveform.Length, "the last block is partial")
+	assert.Equal(t, 12*time.Millisecond, waveform.Duration())
+
+	lo, hi := waveform.Peak(0, 1)
+	assert.Equal(t, int16(6554), lo)  // 0.2
+	assert.Equal(t, int16(11469), hi) // 0.35
+	lo, hi = waveform.Peak(1, 2)
+	assert.Equal(t, int16(0), lo)
+	assert.Equal(t, int16(0), hi)
+	require.NoError(t, waveform.Validate())
+
+	args, err := os.ReadFile(argsFile)
+	require.NoError(t, err)
+	assert.Contains(t, string(args), "-t raw -e floating-point -b 32 --endian little -c 2 -r 1000 -")
+
+	// 8-bit peaks of the mixdown, with the block size from the pixel rate
+	waveform, err = task.Peaks(context.Background(), bytes.NewReader(nil), PeaksOptions{PixelsPerSecond: 200, Bits: 8})
+	require.NoError(t, err)
+	assert.Equal(t, 5, waveform.SamplesPerPixel)
+	assert.Equal(t, 1, waveform.Channels)
+	assert.Equal(t, 8, waveform.Bits)
+
+	args, err = os.ReadFile(argsFile)
+	require.NoError(t, err)
+	assert.Contains(t, string(args), "-c 1 -r 1000 -")
+}
+
+// TestPeaks_Binary verifies .dat files round-trip in the audiowaveform layout
+func TestPeaks_Binary(t *testing.T) {
+	waveform := &Waveform{Version: 2, Channels: 2, SampleRate: 8000, SamplesPerPixel: 256, Bits: 8, Length: 2,
+		Data: []int16{-10, 12, -1, 1, -128, 127, 0, 0}}
+
+	dat, err := waveform.MarshalBinary()
+	require.NoError(t, err)
+	require.Len(t, dat, 24+8)
+	assert.Equal(t, uint32(2), binary.LittleEndian.Uint32(dat[0:]))
+	assert.Equal(t, uint32(1), binary.LittleEndian.Uint32(dat[4:]), "8-bit flag")
+	assert.Equal(t, uint32(8000), binary.LittleEndian.Uint32(dat[8:]))
+	assert.Equal(t, uint32(256), binary.LittleEndian.Uint32(dat[12:]))
+	assert.Equal(t, uint32(2), binary.LittleEndian.Uint32(dat[16:]))
+	assert.Equal(t, uint32(2), binary.LittleEndian.Uint32(dat[20:]))
+	assert.Equal(t, byte(0xf6), dat[24])
+
+	var decoded Waveform
+	require.NoError(t, decoded.UnmarshalBinary(dat))
+	assert.Equal(t, *waveform, decoded)
+
+	// Version 1: 16-bit, single channel, no channel count
+	mono := &Waveform{Version: 1, Channels: 1, SampleRate: 44100, SamplesPerPixel: 512, Bits: 16, Length: 1, Data: []int16{-300, 2000}}
+	dat, err = mono.MarshalBinary()
+	require.NoError(t, err)
+	require.Len(t, dat, 20+4)
+
+	require.NoError(t, decoded.UnmarshalBinary(dat))
+	assert.Equal(t, *mono, decoded)
+
+	waveform.Version = 1
+	_, err = waveform.MarshalBinary()
+	assert.ErrorIs(t, err, ErrInvalidWaveform, "version 1 is single channel")
+
+	assert.ErrorIs(t, decoded.UnmarshalBinary(dat[:22]), ErrInvalidWaveform)
+	assert.ErrorIs(t, decoded.UnmarshalBinary([]byte{1, 2}), ErrInvalidWaveform)
+}
+
+// TestPeaks_JSON verifies the audiowaveform JSON layout, including version 1 files
+func TestPeaks_JSON(t *testing.T) {
+	waveform := &Waveform{Version: 2, Channels: 1, SampleRate: 8000, SamplesPerPixel: 80, Bits: 8, Length: 1, Data: []int16{-3, 4}}
+
+	data, err := json.Marshal(waveform)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"version":2,"channels":1,"sample_rate":8000,"samples_per_pixel":80,"bits":8,"length":1,"data":[-3,4]}`, string(data))
+
+	var decoded Waveform
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, *waveform, decoded)
+
+	require.NoError(t, json.Unmarshal([]byte(`{"sample_rate":44100,"samples_per_pixel":256,"bits":16,"length":2,"data":[0,1,-2,3]}`), &decoded))
+	assert.Equal(t, 1, decoded.Version)
+	assert.Equal(t, 1, decoded.Channels)
+
+	err = json.Unmarshal([]byte(`{"sample_rate":44100,"samples_per_pixel":256,"bits":16,"length":5,"data":[0,1]}`), &decoded)
+	assert.ErrorIs(t, err, ErrInvalidWaveform)
+}
+
+// TestPeaks_Options verifies invalid options are rejected
+func TestPeaks_Options(t *testing.T) {
+	assert.NoError(t, PeaksOptions{}.Validate())
+	assert.Error(t, PeaksOptions{SamplesPerPixel: 256, PixelsPerSecond: 20}.Validate())
+	assert.Error(t, PeaksOptions{Bits: 12}.Validate())
+	assert.Error(t, PeaksOptions{SamplesPerPixel: -1}.Validate())
+
+	_, err := Peaks(context.Background(), bytes.NewReader(nil), PCM_RAW_8K_MONO, PeaksOptions{Bits: 24})
+	assert.ErrorContains(t, err, "bits")
+
+	_, err = Peaks(context.Background(), bytes.NewReader(nil), AudioFormat{Type: TYPE_WAV}, PeaksOptions{PerChannel: true})
+	assert.ErrorContains(t, err, "requires Input.Channels")
+}
+
+// TEST SUITE 27: Loudness
+// ═══════════════════════════════════════════════════════════
+
+// sine returns interleaved samples of a sine in every channel
+func sine(rate, channels int, frequency, amplitude, phase float64, d time.Duration) []float32 {
+	frames := int(int64(rate) * int64(d) / int64(time.Second))
+	samples := make([]float32, 0, frames*channels)
+
+	for i := 0; i < frames; i++ {
+		v := float32(amplitude * math.Sin(2*math.Pi*frequency*float64(i)/float64(rate)+phase))
+		for ch := 0; ch < channels; ch++ {
+			samples = append(samples, v)
+		}
+	}
+
+	return samples
+}
+
+// TestLoudness_Meter verifies BS.1770 loudness of reference tones
+func TestLoudness_Meter(t *testing.T) {
+	// A 1 kHz mono tone at -20 dBFS reads -23 LUFS (K-weighting is ~0 dB at 1 kHz)
+	meter := newLoudnessMeter(48000, 1)
+	meter.add(sine(48000, 1, 1000, 0.1, 0, 5*time.Second))
+	loudness := meter.result()
+
+	assert.InDelta(t, -23.01, loudness.Integrated, 0.05)
+	assert.InDelta(t, 0, loudness.Range, 0.1)
+	assert.InDelta(t, -20, loudness.TruePeak, 0.05)
+
+	// The same tone in both channels of a stereo file is 3 LU louder
+	meter = newLoudnessMeter(44100, 2)
+	meter.add(sine(44100, 2, 1000, 0.1, 0, 5*time.Second))
+	assert.InDelta(t, -20.0, meter.result().Integrated, 0.05)
+
+	// The 5.1 LFE channel is ignored and the surround channels weighted
+	assert.Equal(t, []float64{1, 1, 1, 0, 1.41, 1.41}, channelWeights(6))
+}
+
+// TestLoudness_TruePeak verifies peaks between samples are found by oversampling
+func TestLoudness_TruePeak(t *testing.T) {
+	// fs/4 at 45 degrees: every sample is at 0.707 of the -6 dBFS peak
+	meter := newLoudnessMeter(48000, 1)
+	samples := sine(48000, 1, 12000, 0.5, math.Pi/4, time.Second)
+	meter.add(samples)
+
+	samplePeak := 0.0
+	for _, v := range samples {
+		samplePeak = max(samplePeak, math.Abs(float64(v)))
+	}
+
+	assert.InDelta(t, -9.03, 20*math.Log10(samplePeak), 0.01)
+	assert.InDelta(t, -6.02, meter.result().TruePeak, 0.5)
+}
+
+// TestLoudness_Gating verifies the gates and the loudness range
+func TestLoudness_Gating(t *testing.T) {
+	// Silence and audio shorter than a gating block have no integrated loudness
+	meter := newLoudnessMeter(16000, 1)
+	meter.add(make([]float32, 16000))
+	assert.True(t, math.IsInf(meter.result().Integrated, -1))
+
+	meter = newLoudnessMeter(16000, 1)
+	meter.add(sine(16000, 1, 1000, 0.5, 0, 300*time.Millisecond))
+	assert.True(t, math.IsInf(meter.result().Integrated, -1))
+
+	// Silence is gated out of the integrated loudness
+	meter = newLoudnessMeter(16000, 1)
+	meter.add(sine(16000, 1, 1000, 0.1, 0, 5*time.Second))
+	meter.add(make([]float32, 5*16000))
+	assert.InDelta(t, -23.0, meter.result().Integrated, 0.1)
+
+	// Two passages 10 dB apart span a 10 LU loudness range
+	meter = newLoudnessMeter(16000, 1)
+	meter.add(sine(16000, 1, 1000, 0.1, 0, 20*time.Second))
+	meter.add(sine(16000, 1, 1000, 0.0316, 0, 20*time.Second))
+	assert.InDelta(t, 10, meter.result().Range, 0.2)
+}
+
+// TestLoudness_Measure verifies MeasureLoudness decodes every channel
+func TestLoudness_Measure(t *testing.T) {
+	tmpDir := t.TempDir()
+	decoded := filepath.Join(tmpDir, "decoded.f32")
+	argsFile := filepath.Join(tmpDir, "args.txt")
+	require.NoError(t, os.WriteFile(decoded, floatPCM(sine(8000, 2, 1000, 0.1, 0, 2*time.Second)), 0644))
+
+	opts := DefaultOptions()
+	opts.SoxPath = fakeSox(t, `echo "$@" > "`+argsFile+`"
+cat > /dev/null
+cat "`+decoded+`"
+`)
+
+	format := AudioFormat{Type: TYPE_RAW, Encoding: SIGNED_INTEGER, BitDepth: 16, SampleRate: 8000, Channels: 2}
+	loudness, err := New(format, format).WithOptions(opts).MeasureLoudness(context.Background(), bytes.NewReader(nil))
+	require.NoError(t, err)
+	assert.InDelta(t, -20.0, loudness.Integrated, 0.1)
+
+	args, err := os.ReadFile(argsFile)
+	require.NoError(t, err)
+	assert.Contains(t, string(args), "-c 2 -r 8000 -")
+
+	_, err = MeasureLoudness(context.Background(), bytes.NewReader(nil), AudioFormat{Type: TYPE_WAV})
+	assert.ErrorContains(t, err, "requires Input.Channels")
+}
+
+// TestLoudness_Normalize verifies the measured gain and limiter are applied
+func TestLoudness_Normalize(t *testing.T) {
+	tmpDir := t.TempDir()
+	decoded := filepath.Join(tmpDir, "decoded.f32")
+	argsFile := filepath.Join(tmpDir, "args.txt")
+	// -23 LUFS with a -20 dBTP peak
+	require.NoError(t, os.WriteFile(decoded, floatPCM(sine(8000, 1, 1000, 0.1, 0, 2*time.Second)), 0644))
+
+	opts := DefaultOptions()
+	opts.Effects = []string{"highpass", "100"}
+	opts.SoxPath = fakeSox(t, `echo "$@" > "`+argsFile+`"
+cat > /dev/null
+case "$*" in *floating-point*) cat "`+decoded+`" ;; esac
+`)
+
+	format := AudioFormat{Type: TYPE_RAW, Encoding: SIGNED_INTEGER, BitDepth: 16, SampleRate: 8000, Channels: 1}
+	task := New(format, ULAW_8K_MONO).WithOptions(opts)
+
+	// +7 dB fits under the ceiling
+	measured, err := task.NormalizeLoudness(context.Background(), bytes.NewReader(make([]byte, 64)), io.Discard,
+		LoudnessTarget{Integrated: -16, TruePeak: -1})
+	require.NoError(t, err)
+	assert.InDelta(t, -23.0, measured.Integrated, 0.1)
+
+	args, err := os.ReadFile(argsFile)
+	require.NoError(t, err)
+	assert.Regexp(t, `highpass 100 gain 6\.9\d\n$|highpass 100 gain 7\.0\d\n$`, string(args))
+	assert.Equal(t, []string{"highpass", "100"}, task.Options.Effects, "the Task options are left alone")
+
+	// +22 dB would reach +2 dBTP: limit at the ceiling
+	_, err = task.NormalizeLoudness(context.Background(), bytes.NewReader(make([]byte, 64)), io.Discard,
+		LoudnessTarget{Integrated: -1, TruePeak: -1})
+	require.NoError(t, err)
+
+	args, err = os.ReadFile(argsFile)
+	require.NoError(t, err)
+	assert.Regexp(t, `gain -l 2[23]\.\d\d gain -1\.00\n$`, string(args))
+
+	// MaxGain caps the boost
+	_, err = task.NormalizeLoudness(context.Background(), bytes.NewReader(make([]byte, 64)), io.Discard,
+		LoudnessTarget{Integrated: -10, TruePeak: 0, MaxGain: 3})
+	require.NoError(t, err)
+
+	args, err = os.ReadFile(argsFile)
+	require.NoError(t, err)
+	assert.True(t, strings.HasSuffix(string(args), "gain 3.00\n"), string(args))
+
+	_, err = task.NormalizeLoudness(context.Background(), "in.raw", io.Discard, LoudnessTarget{Integrated: 3})
+	assert.ErrorContains(t, err, "integrated loudness")
+}
+
+// TestLoudness_NormalizeTimeoutPerAttempt verifies Options.Timeout bounds each
+// conversion attempt rather than all of them together
+func TestLoudness_NormalizeTimeoutPerAttempt(t *testing.T) {
+	tmpDir := t.TempDir()
+	decoded := filepath.Join(tmpDir, "decoded.f32")
+	runs := filepath.Join(tmpDir, "runs.txt")
+	require.NoError(t, os.WriteFile(decoded, floatPCM(sine(8000, 1, 1000, 0.1, 0, time.Second)), 0644))
+
+	opts := DefaultOptions()
+	opts.Timeout = time.Second
+	opts.SoxPath = fakeSox(t, `cat > /dev/null
+case "$*" in *floating-point*) cat "`+decoded+`"; exit 0 ;; esac
+echo run >> "`+runs+`"
+sleep 0.6
+[ "$(wc -l < "`+runs+`")" -lt 2 ] && exit 2
+exit 0
+`)
+
+	retry := RetryConfig{MaxAttempts: 2, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond, BackoffMultiple: 1}
+	format := AudioFormat{Type: TYPE_RAW, Encoding: SIGNED_INTEGER, BitDepth: 16, SampleRate: 8000, Channels: 1}
+	task := New(format, ULAW_8K_MONO).WithOptions(opts).WithRetryConfig(retry)
+
+	_, err := task.NormalizeLoudness(context.Background(), bytes.NewReader(make([]byte, 64)), io.Discard,
+		LoudnessTarget{Integrated: -16, TruePeak: -1})
+	require.NoError(t, err)
+
+	log, err := os.ReadFile(runs)
+	require.NoError(t, err)
+	assert.Equal(t, 2, strings.Count(string(log), "\n"))
+}
+
+// TEST SUITE 28: Conversion Reports
+// ═══════════════════════════════════════════════════════════
+
+// soxWarnings is the stderr of a sox run that clipped in two places, at -V3
+const soxWarnings = `sox INFO sox: effects chain: input        16000Hz  1 channels
+sox INFO sox: effects chain: rate          8000Hz  1 channels
+sox INFO sox: effects chain: dither        8000Hz  1 channels
+sox INFO sox: effects chain: output        8000Hz  1 channels
+sox WARN rate: rate clipped 53 samples; decrease volume?
+sox WARN sox: ` + "`" + `out file.wav' output clipped 1234 samples; decrease volume?
+sox WARN wav: Premature EOF on .wav input file
+`
+
+// TestReport_Parse verifies clipping, dither and warnings are parsed from stderr
+func TestReport_Parse(t *testing.T) {
+	report := parseReport([]byte(soxWarnings))
+
+	assert.Equal(t, int64(1287), report.ClippedSamples)
+	assert.Equal(t, []Clip{{Stage: "rate", Samples: 53}, {Stage: "output", Samples: 1234}}, report.Clips)
+	assert.True(t, report.DitherApplied)
+	require.Len(t, report.Warnings, 3)
+	assert.Equal(t, "wav: Premature EOF on .wav input file", report.Warnings[2])
+
+	report = parseReport(nil)
+	assert.Zero(t, report.ClippedSamples)
+	assert.False(t, report.DitherApplied)
+	assert.Empty(t, report.Warnings)
+}
+
+// TestReport_Handler verifies successful conversions report their warnings
+func TestReport_Handler(t *testing.T) {
+	tmpDir := t.TempDir()
+	warnings := filepath.Join(tmpDir, "warnings.txt")
+	require.NoError(t, os.WriteFile(warnings, []byte(soxWarnings), 0644))
+
+	opts := DefaultOptions()
+	opts.SoxPath = fakeSox(t, `cat
+cat "`+warnings+`" >&2
+`)
+
+	var reports []*ConversionReport
+	task := New(PCM_RAW_8K_MONO, PCM_RAW_8K_MONO).WithOptions(opts).WithReportHandler(func(r *ConversionReport) {
+		reports = append(reports, r)
+	})
+
+	var output bytes.Buffer
+	require.NoError(t, task.Convert(bytes.NewReader([]byte{1, 2, 3, 4}), &output))
+	assert.Equal(t, []byte{1, 2, 3, 4}, output.Bytes())
+	require.Len(t, reports, 1)
+	assert.Equal(t, int64(1287), reports[0].ClippedSamples)
+
+	// Streams report when they stop
+	stream := New(PCM_RAW_8K_MONO, PCM_RAW_8K_MONO).WithOptions(opts).WithStream().WithReportHandler(func(r *ConversionReport) {
+		reports = append(reports, r)
+	})
+	require.NoError(t, stream.Start())
+	_, err := stream.Write([]byte{1, 2})
+	require.NoError(t, err)
+	require.NoError(t, stream.Stop())
+	require.Len(t, reports, 2)
+	assert.Len(t, reports[1].Clips, 2)
+}
+
+// TestReport_FailOnClip verifies clipping fails the conversion without retries or
+// tripping the circuit breaker
+func TestReport_FailOnClip(t *testing.T) {
+	tmpDir := t.TempDir()
+	runs := filepath.Join(tmpDir, "runs.txt")
+
+	opts := DefaultOptions()
+	opts.FailOnClip = true
+	opts.SoxPath = fakeSox(t, `echo run >> "`+runs+`"
+cat
+echo "sox WARN gain: gain clipped 7 samples; decrease volume?" >&2
+`)
+
+	breaker := NewCircuitBreakerWithConfig(1, time.Minute, 1)
+	task := New(PCM_RAW_8K_MONO, PCM_RAW_8K_MONO).WithOptions(opts).WithCircuitBreaker(breaker)
+
+	err := task.Convert(bytes.NewReader([]byte{1, 2}), io.Discard)
+	require.ErrorIs(t, err, ErrClipped)
+	assert.Contains(t, err.Error(), "7 samples")
+	assert.Equal(t, StateClosed, breaker.State())
+
+	data, err := os.ReadFile(runs)
+	require.NoError(t, err)
+	assert.Equal(t, "run\n", string(data), "clipping is not retried")
+
+	// Without FailOnClip the run succeeds
+	task.Options.FailOnClip = false
+	assert.NoError(t, task.Convert(bytes.NewReader([]byte{1, 2}), io.Discard))
+}
+
+// TEST SUITE 29: Conversion Results
+// ═══════════════════════════════════════════════════════════
+
+// TestResult_Stream verifies a stream conversion reports its run, byte counts and
+// playing times
+func TestResult_Stream(t *testing.T) {
+	opts := DefaultOptions()
+	opts.SoxPath = fakeSox(t, `cat
+echo "sox WARN wav: Premature EOF on .wav input file" >&2
+`)
+	task := New(PCM_RAW_8K_MONO, PCM_RAW_8K_MONO).WithOptions(opts)
+
+	pcm := generatePCMData(8000, 1000)
+	var output bytes.Buffer
+
+	result, err := task.ConvertWithResult(context.Background(), bytes.NewReader(pcm), &output)
+	require.NoError(t, err)
+
+	require.Len(t, result.Attempts, 1)
+	assert.NoError(t, result.Attempts[0].Err)
+	assert.Positive(t, result.Attempts[0].Duration)
+	assert.GreaterOrEqual(t, result.Duration, result.Attempts[0].Duration)
+	assert.Equal(t, 0, result.ExitCode)
+	assert.Equal(t, opts.SoxPath, result.Args[0])
+	assert.Equal(t, "-", result.Args[len(result.Args)-1])
+
+	assert.Equal(t, int64(len(pcm)), result.BytesIn)
+	assert.Equal(t, int64(output.Len()), result.BytesOut)
+	assert.Equal(t, time.Second, result.InputDuration)
+	assert.Equal(t, time.Second, result.OutputDuration)
+	assert.Equal(t, []string{"wav: Premature EOF on .wav input file"}, result.Report.Warnings)
+
+	assert.Equal(t, result.Attempts[0].UserTime, result.UserTime)
+	if runtime.GOOS == "linux" || runtime.GOOS == "darwin" {
+		assert.Positive(t, result.MaxRSS)
+	}
+}
+
+// TestResult_Retries verifies failed attempts are reported, and the result is
+// returned when the conversion fails
+func TestResult_Retries(t *testing.T) {
+	tmpDir := t.TempDir()
+	runs := filepath.Join(tmpDir, "runs.txt")
+
+	opts := DefaultOptions()
+	opts.SoxPath = fakeSox(t, `echo run >> "`+runs+`"
+[ "$(wc -l < "`+runs+`")" -lt 2 ] && exit 2
+cat
+`)
+	retry := RetryConfig{MaxAttempts: 2, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond, BackoffMultiple: 1}
+	task := New(PCM_RAW_8K_MONO, PCM_RAW_8K_MONO).WithOptions(opts).WithRetryConfig(retry)
+
+	result, err := task.ConvertWithResult(context.Background(), bytes.NewReader([]byte{1, 2, 3, 4}), io.Discard)
+	require.NoError(t, err)
+	require.Len(t, result.Attempts, 2)
+	assert.Equal(t, 2, result.Attempts[0].ExitCode)
+	assert.Error(t, result.Attempts[0].Err)
+	assert.Equal(t, 0, result.Attempts[1].ExitCode)
+	assert.Equal(t, int64(4), result.BytesOut)
+	assert.Equal(t, result.Attempts[0].UserTime+result.Attempts[1].UserTime, result.UserTime)
+
+	opts.SoxPath = fakeSox(t, "exit 3\n")
+	task = New(PCM_RAW_8K_MONO, PCM_RAW_8K_MONO).WithOptions(opts).WithRetryConfig(retry)
+
+	result, err = task.ConvertWithResult(context.Background(), bytes.NewReader([]byte{1, 2}), io.Discard)
+	require.Error(t, err)
+	require.NotNil(t, result)
+	assert.Len(t, result.Attempts, 2)
+	assert.Equal(t, 3, result.ExitCode)
+	assert.Nil(t, result.Report)
+}
+
+// TestResult_Paths verifies path conversions report file sizes and probed
+// playing times
+func TestResult_Paths(t *testing.T) {
+	tmpDir := t.TempDir()
+	summary := filepath.Join(tmpDir, "summary.txt")
+	require.NoError(t, os.WriteFile(summary, []byte(soxiWAV), 0644))
+
+	opts := DefaultOptions()
+	opts.SoxPath = fakeSox(t, `if [ "$1" = --i ]; then
+	cat "`+summary+`"
+	exit 0
+fi
+for a; do
+	case "$a" in
+	*.wav) if [ -z "$in" ]; then in=$a; else out=$a; fi ;;
+	esac
+done
+cp "$in" "$out"
+`)
+	task := New(AudioFormat{Type: TYPE_WAV}, AudioFormat{}).WithOptions(opts)
+
+	wav := buildWAV(generatePCMData(16000, 2500), 16000, 1, 16)
+	input := filepath.Join(tmpDir, "call.wav")
+	output := filepath.Join(tmpDir, "out.wav")
+	require.NoError(t, os.WriteFile(input, wav, 0644))
+
+	result, err := task.ConvertWithResult(context.Background(), input, output)
+	require.NoError(t, err)
+	require.Len(t, result.Attempts, 1)
+	assert.Contains(t, result.Args, input)
+	assert.Contains(t, result.Args, output)
+	assert.Equal(t, int64(len(wav)), result.BytesIn)
+	assert.Equal(t, int64(len(wav)), result.BytesOut)
+	assert.Equal(t, 2500*time.Millisecond, result.InputDuration)
+	assert.Equal(t, 2500*time.Millisecond, result.OutputDuration)
+}
+
+// TestResult_ProbeTimeout verifies the probes get their own Options.Timeout after
+// a slow conversion and are skipped once ctx is done
+func TestResult_ProbeTimeout(t *testing.T) {
+	tmpDir := t.TempDir()
+	summary := filepath.Join(tmpDir, "summary.txt")
+	probes := filepath.Join(tmpDir, "probes.txt")
+	require.NoError(t, os.WriteFile(summary, []byte(soxiWAV), 0644))
+
+	opts := DefaultOptions()
+	opts.Timeout = time.Second
+	opts.SoxPath = fakeSox(t, `if [ "$1" = --i ]; then
+	echo probe >> "`+probes+`"
+	sleep 0.4
+	cat "`+summary+`"
+	exit 0
+fi
+for a; do
+	case "$a" in
+	*.wav) if [ -z "$in" ]; then in=$a; else out=$a; fi ;;
+	esac
+done
+sleep 0.7
+cp "$in" "$out"
+`)
+	task := New(AudioFormat{Type: TYPE_WAV}, AudioFormat{}).WithOptions(opts)
+
+	input := filepath.Join(tmpDir, "call.wav")
+	output := filepath.Join(tmpDir, "out.wav")
+	require.NoError(t, os.WriteFile(input, buildWAV(generatePCMData(16000, 2500), 16000, 1, 16), 0644))
+
+	result, err := task.ConvertWithResult(context.Background(), input, output)
+	require.NoError(t, err)
+	assert.Equal(t, 2500*time.Millisecond, result.InputDuration)
+	assert.Equal(t, 2500*time.Millisecond, result.OutputDuration)
+
+	require.NoError(t, os.Remove(probes))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.Zero(t, task.playingTime(ctx, task.Input, input, 0))
+	assert.NoFileExists(t, probes, "no probe after ctx is done")
+}
+
 // BENCHMARK TESTS
 // ═══════════════════════════════════════════════════════════
 
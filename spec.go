@@ -0,0 +1,324 @@
+package sox
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Format specs describe an AudioFormat in a single string, for config files,
+// CLI flags and log lines:
+//
+//	TYPE[:ENCODING][@RATE[/CHANNELS]]
+//
+// ENCODING is a compact sample encoding such as s16le, u8, f32be, ulaw, alaw,
+// ima-adpcm or gsm, or a bare bit depth such as 16. RATE is in Hz and accepts a
+// "k" suffix (8k, 44.1k). Examples:
+//
+//	raw:s16le@8000/1
+//	raw:ulaw@8000/1
+//	flac@16000/1
+//	wav:s24@48k/2
+
+// specEncodingPattern matches compact PCM encodings such as s16le, u8 or f32be
+var specEncodingPattern = regexp.MustCompile(`^([suf])(\d+)(le|be)?$`)
+
+// specEncodingNames maps named encodings to their sox encoding and implied bit depth
+var specEncodingNames = map[string]struct {
+	encoding Encoding
+	bits     int
+}{
+	"ulaw":      {MU_LAW, 8},
+	"mulaw":     {MU_LAW, 8},
+	"mu-law":    {MU_LAW, 8},
+	"alaw":      {A_LAW, 8},
+	"a-law":     {A_LAW, 8},
+	"ima":       {IMA_ADPCM, 4},
+	"ima-adpcm": {IMA_ADPCM, 4},
+	"ms":        {MS_ADPCM, 4},
+	"ms-adpcm":  {MS_ADPCM, 4},
+	"gsm":       {GSM_FULL_RATE, 0},
+}
+
+// specEncodingPrefixes maps compact PCM prefixes to sox encodings
+var specEncodingPrefixes = map[string]Encoding{
+	"s": SIGNED_INTEGER,
+	"u": UNSIGNED_INTEGER,
+	"f": FLOATING_POINT,
+}
+
+// ParseFormat parses a format spec such as "raw:s16le@8000/1" or "flac@16000/1".
+// Only the core fields (Type, Encoding, BitDepth, Endian, SampleRate, Channels) can
+// be expressed in a spec. Preset names such as "ULAW_8K_MONO" are accepted too,
+// and take precedence over specs.
+//
+// Example:
+//
+//	input, err := sox.ParseFormat("raw:ulaw@8000/1")
+//	output, err := sox.ParseFormat("flac@16k/1")
+//	task := sox.New(input, output)
+func ParseFormat(spec string) (AudioFormat, error) {
+	var f AudioFormat
+
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return f, fmt.Errorf("%w: empty format spec", ErrInvalidFormat)
+	}
+
+	if preset, ok := Preset(spec); ok {
+		return preset, nil
+	}
+
+	head, params, hasParams := strings.Cut(spec, "@")
+	typ, enc, hasEnc := strings.Cut(head, ":")
+
+	f.Type = strings.ToLower(typ)
+	if hasEnc {
+		if err := f.parseSpecEncoding(strings.ToLower(enc)); err != nil {
+			return AudioFormat{}, fmt.Errorf("%w: spec %q: %v", ErrInvalidFormat, spec, err)
+		}
+	}
+
+	if hasParams {
+		if err := f.parseSpecParams(params); err != nil {
+			return AudioFormat{}, fmt.Errorf("%w: spec %q: %v", ErrInvalidFormat, spec, err)
+		}
+	}
+
+	if f.Type == "" && !hasEnc && !hasParams {
+		return AudioFormat{}, fmt.Errorf("%w: spec %q: missing type", ErrInvalidFormat, spec)
+	}
+
+	return f, nil
+}
+
+// parseSpecEncoding fills Encoding, BitDepth and Endian from the ENCODING part of a spec
+func (f *AudioFormat) parseSpecEncoding(enc string) error {
+	if m := specEncodingPattern.FindStringSubmatch(enc); m != nil {
+		bits, _ := strconv.Atoi(m[2])
+		f.Encoding = specEncodingPrefixes[m[1]]
+		f.BitDepth = bits
+
+		switch m[3] {
+		case "le":
+			f.Endian = "little"
+		case "be":
+			f.Endian = "big"
+		}
+
+		return nil
+	}
+
+	if named, ok := specEncodingNames[enc]; ok {
+		f.Encoding = named.encoding
+		f.BitDepth = named.bits
+		return nil
+	}
+
+	if bits, err := strconv.Atoi(enc); err == nil && bits > 0 {
+		f.BitDepth = bits
+		return nil
+	}
+
+	return fmt.Errorf("unknown encoding %q", enc)
+}
+
+// parseSpecParams fills SampleRate and Channels from the RATE[/CHANNELS] part of a spec
+func (f *AudioFormat) parseSpecParams(params string) error {
+	rate, channels, hasChannels := strings.Cut(params, "/")
+
+	if rate != "" {
+		hz, err := parseSpecRate(rate)
+		if err != nil {
+			return err
+		}
+		f.SampleRate = hz
+	}
+
+	if hasChannels {
+		n, err := strconv.Atoi(channels)
+		if err != nil || n <= 0 {
+			return fmt.Errorf("invalid channels %q", channels)
+		}
+		f.Channels = n
+	}
+
+	return nil
+}
+
+// parseSpecRate parses a sample rate in Hz, accepting a "k" suffix
+func parseSpecRate(rate string) (int, error) {
+	rate = strings.ToLower(rate)
+
+	multiplier := 1.0
+	if strings.HasSuffix(rate, "k") {
+		multiplier = 1000
+		rate = strings.TrimSuffix(rate, "k")
+	}
+
+	value, err := strconv.ParseFloat(rate, 64)
+	if err != nil || value <= 0 {
+		return 0, fmt.Errorf("invalid sample rate %q", rate)
+	}
+
+	return int(value*multiplier + 0.5), nil
+}
+
+// String returns the format spec of the core fields, e.g. "raw:s16le@8000/1".
+// Extended options such as Volume or CustomArgs are not part of the spec.
+func (f AudioFormat) String() string {
+	var b strings.Builder
+
+	b.WriteString(f.Type)
+
+	if enc := f.specEncoding(); enc != "" {
+		b.WriteString(":")
+		b.WriteString(enc)
+	}
+
+	if f.SampleRate > 0 || f.Channels > 0 {
+		b.WriteString("@")
+		if f.SampleRate > 0 {
+			b.WriteString(strconv.Itoa(f.SampleRate))
+		}
+		if f.Channels > 0 {
+			b.WriteString("/")
+			b.WriteString(strconv.Itoa(f.Channels))
+		}
+	}
+
+	return b.String()
+}
+
+// specEncoding renders Encoding, BitDepth and Endian as the ENCODING part of a spec
+func (f AudioFormat) specEncoding() string {
+	encoding := f.Encoding.Canonical()
+
+	for prefix, name := range specEncodingPrefixes {
+		if encoding == name && f.BitDepth > 0 {
+			switch f.Endian {
+			case "little":
+				return fmt.Sprintf("%s%dle", prefix, f.BitDepth)
+			case "big":
+				return fmt.Sprintf("%s%dbe", prefix, f.BitDepth)
+			}
+			return fmt.Sprintf("%s%d", prefix, f.BitDepth)
+		}
+	}
+
+	switch encoding {
+	case MU_LAW:
+		return "ulaw"
+	case A_LAW:
+		return "alaw"
+	case IMA_ADPCM, MS_ADPCM:
+		return string(encoding)
+	case GSM_FULL_RATE:
+		return "gsm"
+	case "":
+		if f.BitDepth > 0 {
+			return strconv.Itoa(f.BitDepth)
+		}
+	}
+
+	return string(encoding)
+}
+
+// hasSpec reports whether the format is fully described by its spec string
+func (f AudioFormat) hasSpec() bool {
+	parsed, err := ParseFormat(f.String())
+	return err == nil && reflect.DeepEqual(parsed, f)
+}
+
+// audioFormatFields mirrors AudioFormat without its marshaling methods, and is used
+// for the object form when a format cannot be written as a spec string
+type audioFormatFields struct {
+	Type           string   `json:"type,omitempty" yaml:"type,omitempty"`
+	Encoding       Encoding `json:"encoding,omitempty" yaml:"encoding,omitempty"`
+	SampleRate     int      `json:"sample_rate,omitempty" yaml:"sample_rate,omitempty"`
+	Channels       int      `json:"channels,omitempty" yaml:"channels,omitempty"`
+	BitDepth       int      `json:"bit_depth,omitempty" yaml:"bit_depth,omitempty"`
+	Volume         float64  `json:"volume,omitempty" yaml:"volume,omitempty"`
+	IgnoreLength   bool     `json:"ignore_length,omitempty" yaml:"ignore_length,omitempty"`
+	ReverseNibbles bool     `json:"reverse_nibbles,omitempty" yaml:"reverse_nibbles,omitempty"`
+	ReverseBits    bool     `json:"reverse_bits,omitempty" yaml:"reverse_bits,omitempty"`
+	Endian         string   `json:"endian,omitempty" yaml:"endian,omitempty"`
+	Compression    float64  `json:"compression,omitempty" yaml:"compression,omitempty"`
+	Comment        string   `json:"comment,omitempty" yaml:"comment,omitempty"`
+	AddComment     string   `json:"add_comment,omitempty" yaml:"add_comment,omitempty"`
+	CommentFile    string   `json:"comment_file,omitempty" yaml:"comment_file,omitempty"`
+	NoGlob         bool     `json:"no_glob,omitempty" yaml:"no_glob,omitempty"`
+	Pipe           bool     `json:"pipe,omitempty" yaml:"pipe,omitempty"`
+	CustomArgs     []string `json:"custom_args,omitempty" yaml:"custom_args,omitempty"`
+}
+
+// MarshalText implements encoding.TextMarshaler using the format spec
+func (f AudioFormat) MarshalText() ([]byte, error) {
+	return []byte(f.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler by parsing a format spec
+func (f *AudioFormat) UnmarshalText(text []byte) error {
+	parsed, err := ParseFormat(string(text))
+	if err != nil {
+		return err
+	}
+
+	*f = parsed
+	return nil
+}
+
+// MarshalJSON encodes the format as its spec string, or as an object when it
+// uses options a spec cannot express
+func (f AudioFormat) MarshalJSON() ([]byte, error) {
+	if f.hasSpec() {
+		return json.Marshal(f.String())
+	}
+
+	return json.Marshal(audioFormatFields(f))
+}
+
+// UnmarshalJSON accepts either a spec string or an object
+func (f *AudioFormat) UnmarshalJSON(data []byte) error {
+	var spec string
+	if err := json.Unmarshal(data, &spec); err == nil {
+		return f.UnmarshalText([]byte(spec))
+	}
+
+	var fields audioFormatFields
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	*f = AudioFormat(fields)
+	return nil
+}
+
+// MarshalYAML encodes the format as its spec string, or as a mapping when it
+// uses options a spec cannot express
+func (f AudioFormat) MarshalYAML() (interface{}, error) {
+	if f.hasSpec() {
+		return f.String(), nil
+	}
+
+	return audioFormatFields(f), nil
+}
+
+// UnmarshalYAML accepts either a spec string or a mapping
+func (f *AudioFormat) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var spec string
+	if err := unmarshal(&spec); err == nil {
+		return f.UnmarshalText([]byte(spec))
+	}
+
+	var fields audioFormatFields
+	if err := unmarshal(&fields); err != nil {
+		return err
+	}
+
+	*f = AudioFormat(fields)
+	return nil
+}
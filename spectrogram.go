@@ -0,0 +1,198 @@
+package sox
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+)
+
+// SpectrogramWindow is the window function of a spectrogram
+type SpectrogramWindow string
+
+// Spectrogram window functions
+const (
+	WINDOW_HANN        SpectrogramWindow = "Hann"
+	WINDOW_HAMMING     SpectrogramWindow = "Hamming"
+	WINDOW_BARTLETT    SpectrogramWindow = "Bartlett"
+	WINDOW_RECTANGULAR SpectrogramWindow = "Rectangular"
+	WINDOW_KAISER      SpectrogramWindow = "Kaiser"
+	WINDOW_DOLPH       SpectrogramWindow = "Dolph"
+)
+
+// spectrogramWindows is the set of window functions known to sox
+var spectrogramWindows = map[SpectrogramWindow]bool{
+	WINDOW_HANN: true, WINDOW_HAMMING: true, WINDOW_BARTLETT: true,
+	WINDOW_RECTANGULAR: true, WINDOW_KAISER: true, WINDOW_DOLPH: true,
+}
+
+// SpectrogramOptions configures the sox spectrogram effect. Zero values keep the
+// sox defaults.
+type SpectrogramOptions struct {
+	Width           int               // -x: image width in pixels, 100 to 200000; 800 by default
+	Height          int               // -y: height per channel in pixels, 64 to 1200; best as 1 + a power of 2
+	PixelsPerSecond float64           // -X: time resolution; by default fitted to Width
+	DynamicRange    int               // -z: dB range shown below the maximum, 20 to 180; 120 by default
+	Window          SpectrogramWindow // -w: window function; Hann by default
+	Monochrome      bool              // -m: grey scale instead of colour
+	LightBackground bool              // -l: light background, for printing
+	Title           string            // -t: title above the image
+	Comment         string            // -c: comment below the image
+	Start           time.Duration     // -S: start of the plotted range
+	Duration        time.Duration     // -d: length of the plotted range; to the end by default
+	PerChannel      bool              // Plot each channel, stacked; otherwise channels are mixed down
+	Raw             bool              // -r: bare spectrogram, without axes, legend or title
+}
+
+// Validate checks the options against the ranges sox accepts
+func (o SpectrogramOptions) Validate() error {
+	switch {
+	case o.Width != 0 && (o.Width < 100 || o.Width > 200000):
+		return fmt.Errorf("invalid spectrogram options: width must be 100 to 200000 pixels, got %d", o.Width)
+	case o.Height != 0 && (o.Height < 64 || o.Height > 1200):
+		return fmt.Errorf("invalid spectrogram options: height must be 64 to 1200 pixels, got %d", o.Height)
+	case o.PixelsPerSecond < 0:
+		return fmt.Errorf("invalid spectrogram options: pixels per second must not be negative")
+	case o.DynamicRange != 0 && (o.DynamicRange < 20 || o.DynamicRange > 180):
+		return fmt.Errorf("invalid spectrogram options: dynamic range must be 20 to 180 dB, got %d", o.DynamicRange)
+	case o.Window != "" && !spectrogramWindows[o.Window]:
+		return fmt.Errorf("invalid spectrogram options: unknown window %q", o.Window)
+	case o.Start < 0 || o.Duration < 0:
+		return fmt.Errorf("invalid spectrogram options: time range must not be negative")
+	}
+
+	return nil
+}
+
+// args returns the spectrogram effect arguments, without the output file
+func (o SpectrogramOptions) args() []string {
+	args := []string{"spectrogram"}
+
+	if o.Width > 0 {
+		args = append(args, "-x", strconv.Itoa(o.Width))
+	}
+	if o.Height > 0 {
+		args = append(args, "-y", strconv.Itoa(o.Height))
+	}
+	if o.PixelsPerSecond > 0 {
+		args = append(args, "-X", strconv.FormatFloat(o.PixelsPerSecond, 'f', -1, 64))
+	}
+	if o.DynamicRange > 0 {
+		args = append(args, "-z", strconv.Itoa(o.DynamicRange))
+	}
+	if o.Window != "" {
+		args = append(args, "-w", string(o.Window))
+	}
+	if o.Monochrome {
+		args = append(args, "-m")
+	}
+	if o.LightBackground {
+		args = append(args, "-l")
+	}
+	if o.Raw {
+		args = append(args, "-r")
+	}
+	if o.Title != "" {
+		args = append(args, "-t", o.Title)
+	}
+	if o.Comment != "" {
+		args = append(args, "-c", o.Comment)
+	}
+	if o.Start > 0 {
+		args = append(args, "-S", soxSeconds(o.Start))
+	}
+	if o.Duration > 0 {
+		args = append(args, "-d", soxSeconds(o.Duration))
+	}
+
+	return args
+}
+
+// soxSeconds formats a duration as a sox time in seconds
+func soxSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64)
+}
+
+// Spectrogram renders a PNG spectrogram of an audio file or stream. See
+// Task.Spectrogram; Spectrogram uses a Task with the default options and format
+// as its Input.
+//
+// Example:
+//
+//	w.Header().Set("Content-Type", "image/png")
+//	err := sox.Spectrogram(ctx, "call.wav", sox.AudioFormat{}, sox.SpectrogramOptions{Title: "call 1234"}, w)
+func Spectrogram(ctx context.Context, input interface{}, format AudioFormat, options SpectrogramOptions, out io.Writer) error {
+	return New(format, format).Spectrogram(ctx, input, options, out)
+}
+
+// Spectrogram renders a PNG spectrogram of an audio file or stream in the Input
+// format with the sox spectrogram effect, and writes it to out. The input is a
+// file path or an io.Reader. sox writes the image to a temporary file, which is
+// copied to out once sox succeeds, so nothing is written on failure.
+//
+// The rendering runs Options.SoxPath, honors Options.Timeout and goes through the
+// Task circuit breaker. Options.Effects are not applied.
+//
+// Example:
+//
+//	task := sox.New(sox.PCM_RAW_8K_MONO, sox.PCM_RAW_8K_MONO)
+//	file, err := os.Create("complaint-1234.png")
+//	if err != nil {
+//		return err
+//	}
+//	defer file.Close()
+//
+//	err = task.Spectrogram(ctx, bytes.NewReader(pcm), sox.SpectrogramOptions{
+//		Width:        1200,
+//		DynamicRange: 90,
+//		Start:        30 * time.Second,
+//		Duration:     10 * time.Second,
+//		Title:        "ticket 1234, 00:30-00:40",
+//	}, file)
+func (c *Task) Spectrogram(ctx context.Context, input interface{}, options SpectrogramOptions, out io.Writer) error {
+	if err := options.Validate(); err != nil {
+		return err
+	}
+
+	inputArgs, stdin, err := c.soxInput(input)
+	if err != nil {
+		return err
+	}
+
+	image, err := os.CreateTemp("", "sox-spectrogram-*.png")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	image.Close()
+	defer os.Remove(image.Name())
+
+	args := append(c.Options.BuildGlobalArgs(), inputArgs...)
+	args = append(args, "-n")
+	if !options.PerChannel {
+		args = append(args, "channels", "1")
+	}
+	args = append(args, options.args()...)
+	args = append(args, "-o", image.Name())
+
+	err = c.guardedCall(ctx, func(ctx context.Context) error {
+		_, err := c.execSox(ctx, "spectrogram", args, stdin, nil)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(image.Name())
+	if err != nil {
+		return fmt.Errorf("failed to read spectrogram: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := io.Copy(out, file); err != nil {
+		return fmt.Errorf("failed to write spectrogram: %w", err)
+	}
+
+	return nil
+}
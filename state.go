@@ -0,0 +1,132 @@
+package sox
+
+import (
+	"errors"
+	"fmt"
+)
+
+// TaskState represents the lifecycle state of a stream or ticker Task
+type TaskState int
+
+const (
+	TaskIdle     TaskState = iota // Configured, not started yet
+	TaskRunning                   // Started, accepting writes
+	TaskStopping                  // Stop in progress, flushing and waiting for sox
+	TaskStopped                   // Stopped cleanly, may be started again
+	TaskFailed                    // Start or stop failed, must be reset or restarted
+)
+
+// String returns the lowercase name of the state
+func (s TaskState) String() string {
+	switch s {
+	case TaskIdle:
+		return "idle"
+	case TaskRunning:
+		return "running"
+	case TaskStopping:
+		return "stopping"
+	case TaskStopped:
+		return "stopped"
+	case TaskFailed:
+		return "failed"
+	}
+
+	return fmt.Sprintf("TaskState(%d)", int(s))
+}
+
+// ErrInvalidState is matched by every StateError, for use with errors.Is
+var ErrInvalidState = errors.New("invalid task state")
+
+// StateError is returned when a Task operation is not allowed in its current state
+type StateError struct {
+	Op    string    // Operation attempted, e.g. "start" or "write"
+	State TaskState // State the Task was in
+}
+
+func (e *StateError) Error() string {
+	return fmt.Sprintf("cannot %s: task is %s", e.Op, e.State)
+}
+
+// Unwrap allows errors.Is(err, ErrInvalidState)
+func (e *StateError) Unwrap() error {
+	return ErrInvalidState
+}
+
+// State returns the current lifecycle state of the Task
+func (c *Task) State() TaskState {
+	c.stateLock.Lock()
+	defer c.stateLock.Unlock()
+
+	return c.state
+}
+
+// setState moves the Task to the given state unconditionally
+func (c *Task) setState(state TaskState) {
+	c.stateLock.Lock()
+	defer c.stateLock.Unlock()
+
+	c.state = state
+}
+
+// transition moves the Task to the given state if it is currently in one of from
+func (c *Task) transition(op string, to TaskState, from ...TaskState) error {
+	c.stateLock.Lock()
+	defer c.stateLock.Unlock()
+
+	for _, state := range from {
+		if c.state == state {
+			c.state = to
+			return nil
+		}
+	}
+
+	return &StateError{Op: op, State: c.state}
+}
+
+// requireState returns a StateError unless the Task is in one of the given states
+func (c *Task) requireState(op string, states ...TaskState) error {
+	current := c.State()
+	for _, state := range states {
+		if current == state {
+			return nil
+		}
+	}
+
+	return &StateError{Op: op, State: current}
+}
+
+// Restart stops the Task if it is running and starts it again with the same
+// configuration. It is also the way out of the Failed state.
+//
+// Example:
+//
+//	if task.State() == TaskFailed {
+//		if err := task.Restart(); err != nil {
+//			return err
+//		}
+//	}
+func (c *Task) Restart() error {
+	if err := c.Reset(); err != nil {
+		return err
+	}
+
+	return c.Start()
+}
+
+// Reset stops the Task if it is running, discards any buffered audio and
+// returns it to the Idle state, keeping the configuration.
+func (c *Task) Reset() error {
+	if err := c.requireState("reset", TaskIdle, TaskRunning, TaskStopped, TaskFailed); err != nil {
+		return err
+	}
+
+	err := c.Stop()
+
+	c.tickerLock.Lock()
+	c.resetTickerState()
+	c.tickerLock.Unlock()
+
+	c.setState(TaskIdle)
+
+	return err
+}
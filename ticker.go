@@ -0,0 +1,635 @@
+package sox
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultMaxInFlightFlushes allows one flush to convert while the next one is queued
+const defaultMaxInFlightFlushes = 2
+
+// tickerFlush is a batch of audio detached from the ticker buffer, waiting to be converted
+type tickerFlush struct {
+	pending   *bytes.Buffer // audio written since the previous chunk, nil when no chunk is due
+	recording []byte        // snapshot of the whole recording for cumulative output
+
+	// Set by Flush, which waits for the converted audio
+	ctx    context.Context
+	result chan tickerResult
+
+	// Guarded by tickerLock: a Flush cancelled before its conversion started
+	// abandons the job, whose audio is then kept for the next flush
+	started   bool
+	abandoned bool
+}
+
+// tickerResult is the outcome of a flush requested through Flush
+type tickerResult struct {
+	data []byte
+	err  error
+}
+
+// Chunk is the converted output of a single ticker flush.
+// When an overlap is configured, the chunk starts with the tail of the audio
+// already delivered in previous chunks, marked by Overlap and OverlapBytes.
+type Chunk struct {
+	Seq          int           // Flush sequence number, starting at 1
+	Data         []byte        // Converted audio in the Output format
+	Offset       time.Duration // Position of the chunk start in the recording, overlap included
+	Duration     time.Duration // Input audio duration covered by the chunk, overlap included
+	Overlap      time.Duration // Leading region repeated from the preceding audio
+	OverlapBytes int           // Size of the overlapping region in Input bytes
+	Path         string        // File written for this chunk when an output template is set
+}
+
+// ManifestEntry is one line of the ticker index manifest, describing a per-flush output file.
+type ManifestEntry struct {
+	Seq        int    `json:"seq"`
+	Path       string `json:"path"`
+	OffsetMs   int64  `json:"offset_ms"`
+	DurationMs int64  `json:"duration_ms"`
+	OverlapMs  int64  `json:"overlap_ms"`
+	Bytes      int    `json:"bytes"`
+	SHA256     string `json:"sha256"`
+}
+
+// WithOverlap makes each ticker chunk start with the last d of the preceding audio.
+// The overlap is rounded down to whole frames of the Input format, so Input must
+// define SampleRate, BitDepth and Channels.
+//
+// Example:
+//
+//	task := New(PCM_RAW_8K_MONO, FLAC_16K_MONO_LE).
+//		WithTicker(5*time.Second).
+//		WithOverlap(1500*time.Millisecond).
+//		WithChunkHandler(func(chunk Chunk) {
+//			transcribe(chunk.Data, chunk.Overlap)
+//		})
+func (c *Task) WithOverlap(d time.Duration) *Task {
+	c.tickerOverlap = d
+	return c
+}
+
+// WithChunkHandler registers a callback receiving the converted audio of each
+// ticker flush. Every chunk holds the audio written since the previous flush,
+// preceded by the overlap configured with WithOverlap.
+//
+// The handler runs on the flush worker, so it must not call Flush, FlushTo, Stop
+// or Reset: they wait for that worker and would deadlock.
+//
+// Example:
+//
+//	task := New(PCM_RAW_8K_MONO, FLAC_16K_MONO_LE).
+//		WithTicker(3*time.Second).
+//		WithChunkHandler(func(chunk Chunk) {
+//			log.Printf("chunk %d: %d bytes at %s", chunk.Seq, len(chunk.Data), chunk.Offset)
+//		})
+func (c *Task) WithChunkHandler(fn func(Chunk)) *Task {
+	c.tickerHandler = fn
+	return c
+}
+
+// Flush synchronously converts the audio buffered since the previous flush and returns
+// it in the Output format, while the ticker keeps running. It waits for earlier
+// periodic flushes to complete so chunks stay in order, and the converted audio is
+// also delivered to the output template, manifest and chunk handler like a regular
+// tick. It returns nil when nothing was buffered.
+//
+// When ctx is done before the conversion starts, Flush returns the context error
+// and the audio is kept for the next flush. Once the conversion has started, Flush
+// waits for sox to be stopped and returns its audio if it had already finished.
+// Audio whose conversion fails, here or on a tick, is also kept for the next flush.
+//
+// Example:
+//
+//	task := New(PCM_RAW_8K_MONO, FLAC_16K_MONO_LE).WithTicker(30 * time.Second)
+//	task.Start()
+//	defer task.Stop()
+//
+//	// End of an utterance: transcribe what we have without ending the call
+//	flac, err := task.Flush(ctx)
+func (c *Task) Flush(ctx context.Context) ([]byte, error) {
+	if !c.tickerMode {
+		return nil, fmt.Errorf("flush only available in ticker mode")
+	}
+
+	if err := c.requireState("flush", TaskRunning); err != nil {
+		return nil, err
+	}
+
+	result := make(chan tickerResult, 1)
+
+	job, err := c.dispatchTickerFlush(ctx, true, result)
+	if err != nil || job == nil {
+		return nil, err
+	}
+
+	select {
+	case r := <-result:
+		return r.data, r.err
+	case <-ctx.Done():
+	}
+
+	c.tickerLock.Lock()
+	started := job.started
+	job.abandoned = !started
+	c.tickerLock.Unlock()
+
+	if !started {
+		return nil, fmt.Errorf("flush cancelled: %w", ctx.Err())
+	}
+
+	// sox is being stopped through ctx: wait so the audio is either returned
+	// or kept for the next flush
+	r := <-result
+	return r.data, r.err
+}
+
+// FlushTo is like Flush but writes the converted audio to w.
+//
+// Example:
+//
+//	w.Header().Set("Content-Type", "audio/flac")
+//	err := task.FlushTo(r.Context(), w)
+func (c *Task) FlushTo(ctx context.Context, w io.Writer) error {
+	data, err := c.Flush(ctx)
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("failed to write flushed audio: %w", err)
+	}
+
+	return nil
+}
+
+// WithMaxInFlightFlushes limits how many ticker flushes may be queued or converting
+// at once (default 2). Flushes run on a background worker so Write never waits for
+// sox; when the limit is reached because sox falls behind, a tick is skipped and its
+// audio is carried over to the next flush. The buffered audio keeps growing until sox
+// catches up, so size the limit and the tick interval for the expected load.
+//
+// Example:
+//
+//	task := New(PCM_RAW_8K_MONO, FLAC_16K_MONO_LE).
+//		WithTicker(time.Second).
+//		WithMaxInFlightFlushes(4)
+func (c *Task) WithMaxInFlightFlushes(n int) *Task {
+	if n < 1 {
+		n = 1
+	}
+
+	c.tickerMaxInFlight = n
+	c.tickerFree = make(chan *bytes.Buffer, n)
+	return c
+}
+
+// WithOutputTemplate makes ticker mode write one file per flush instead of overwriting
+// the output path on every tick. The template supports the placeholders:
+//   - {dir}: directory of the path set with WithOutputPath ("." when unset)
+//   - {seq}: flush sequence number, zero-padded to 6 digits
+//   - {unix}: Unix time of the flush in seconds
+//   - {offset_ms}: position of the chunk in the recording, in milliseconds
+//
+// Example:
+//
+//	task := New(PCM_RAW_8K_MONO, FLAC_16K_MONO_LE).
+//		WithTicker(3*time.Second).
+//		WithOutputPath("/var/recordings/call-42/full.flac").
+//		WithOutputTemplate("{dir}/{seq}.flac").
+//		WithManifest("/var/recordings/call-42/index.jsonl")
+func (c *Task) WithOutputTemplate(template string) *Task {
+	c.outputTemplate = template
+	return c
+}
+
+// WithManifest appends a JSON line describing every per-flush file written through
+// WithOutputTemplate to the file at path. Each line is a ManifestEntry, written in a
+// single append so that readers tailing the manifest never see partial entries.
+func (c *Task) WithManifest(path string) *Task {
+	c.manifestPath = path
+	return c
+}
+
+// cumulativeOutput reports whether every flush rewrites the output path with the whole recording
+func (c *Task) cumulativeOutput() bool {
+	return c.outputPath != "" && c.outputTemplate == ""
+}
+
+// chunkMode reports whether ticker flushes produce chunks of new audio
+// rather than a single cumulative conversion
+func (c *Task) chunkMode() bool {
+	return c.tickerHandler != nil || c.outputTemplate != ""
+}
+
+// recordingOnly reports whether the recording holds the audio not yet flushed, so
+// Write does not keep a second copy of it in the ticker buffer
+func (c *Task) recordingOnly() bool {
+	return c.cumulativeOutput() && !c.chunkMode()
+}
+
+// pendingLen returns the size of the audio a Flush would convert (assumes lock is held)
+func (c *Task) pendingLen() int {
+	if c.recordingOnly() {
+		return len(c.tickerCarry) + len(c.tickerRecording) - c.tickerTaken
+	}
+
+	return len(c.tickerCarry) + c.tickerBuffer.Len()
+}
+
+// takePending detaches the audio written since the previous flush (assumes lock is held)
+func (c *Task) takePending() *bytes.Buffer {
+	if c.recordingOnly() {
+		buf := c.freeTickerBuffer()
+		buf.Write(c.tickerRecording[c.tickerTaken:])
+		c.tickerTaken = len(c.tickerRecording)
+		return buf
+	}
+
+	buf := c.tickerBuffer
+	c.tickerBuffer = c.freeTickerBuffer()
+	return buf
+}
+
+// expandOutputTemplate resolves the output template placeholders for a chunk
+func (c *Task) expandOutputTemplate(seq int, offset time.Duration, now time.Time) string {
+	dir := "."
+	if c.outputPath != "" {
+		dir = filepath.Dir(c.outputPath)
+	}
+
+	replacer := strings.NewReplacer(
+		"{dir}", dir,
+		"{seq}", fmt.Sprintf("%06d", seq),
+		"{unix}", strconv.FormatInt(now.Unix(), 10),
+		"{offset_ms}", strconv.FormatInt(offset.Milliseconds(), 10),
+	)
+
+	return replacer.Replace(c.outputTemplate)
+}
+
+// appendManifest writes the manifest line for a chunk written to a file
+func (c *Task) appendManifest(chunk Chunk) error {
+	sum := sha256.Sum256(chunk.Data)
+	line, err := json.Marshal(ManifestEntry{
+		Seq:        chunk.Seq,
+		Path:       chunk.Path,
+		OffsetMs:   chunk.Offset.Milliseconds(),
+		DurationMs: chunk.Duration.Milliseconds(),
+		OverlapMs:  chunk.Overlap.Milliseconds(),
+		Bytes:      len(chunk.Data),
+		SHA256:     hex.EncodeToString(sum[:]),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest entry: %w", err)
+	}
+
+	file, err := os.OpenFile(c.manifestPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open manifest: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	return nil
+}
+
+// resetTickerState discards buffered audio and chunk progress (assumes lock is held)
+func (c *Task) resetTickerState() {
+	c.tickerBuffer.Reset()
+	c.tickerRecording = nil
+	c.tickerSeq = 0
+	c.tickerFlushed = 0
+	c.tickerTaken = 0
+	c.tickerWritten = 0
+	c.tickerCarry = nil
+	c.tickerTail = nil
+	c.tickerErrs = nil
+	c.writeCarry = nil
+	c.tickerRun++
+}
+
+// validateOverlap checks that the overlap can be aligned to Input frames
+func (c *Task) validateOverlap() error {
+	if c.tickerOverlap < 0 {
+		return fmt.Errorf("ticker overlap must not be negative")
+	}
+
+	if c.tickerOverlap > 0 && c.Input.BytesPerSecond() == 0 {
+		return fmt.Errorf("ticker overlap requires input sample rate, bit depth and channels")
+	}
+
+	return nil
+}
+
+// overlapBytes returns the overlap length in Input bytes, aligned to whole frames
+func (c *Task) overlapBytes() int {
+	return c.Input.BytesFor(c.tickerOverlap)
+}
+
+// inputDuration converts a byte count of Input audio to a duration
+func (c *Task) inputDuration(n int) time.Duration {
+	return c.Input.Duration(n)
+}
+
+// dispatchTickerFlush detaches the pending audio and queues it for the flush worker,
+// returning the queued job or nil when there was nothing to do. With wait unset it
+// returns immediately when the in-flight limit is reached; otherwise it waits for a
+// slot until ctx is done. When result is set, the converted audio is sent to it.
+func (c *Task) dispatchTickerFlush(ctx context.Context, wait bool, result chan tickerResult) (*tickerFlush, error) {
+	if wait {
+		select {
+		case c.tickerSlots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, fmt.Errorf("flush cancelled: %w", ctx.Err())
+		}
+	} else {
+		select {
+		case c.tickerSlots <- struct{}{}:
+		default:
+			return nil, nil
+		}
+	}
+
+	c.tickerLock.Lock()
+	defer c.tickerLock.Unlock()
+
+	// Periodic flushes only take the audio when a chunk handler or output template
+	// consumes it; otherwise it stays buffered for Flush
+	chunk := (result != nil || c.chunkMode()) && c.pendingLen() > 0
+	rewrite := c.cumulativeOutput() && len(c.tickerRecording) > c.tickerWritten
+
+	if c.tickerQueue == nil || !chunk && !rewrite {
+		<-c.tickerSlots
+		return nil, nil
+	}
+
+	job := &tickerFlush{result: result}
+
+	if result != nil {
+		job.ctx = ctx
+	}
+
+	if chunk {
+		job.pending = c.takePending()
+	}
+
+	if rewrite {
+		job.recording = c.tickerRecording[:len(c.tickerRecording):len(c.tickerRecording)]
+		c.tickerWritten = len(c.tickerRecording)
+	}
+
+	// Never blocks: the queue holds as many jobs as there are slots
+	c.tickerQueue <- job
+
+	return job, nil
+}
+
+// freeTickerBuffer returns a recycled buffer if one is available
+func (c *Task) freeTickerBuffer() *bytes.Buffer {
+	select {
+	case buf := <-c.tickerFree:
+		return buf
+	default:
+		return &bytes.Buffer{}
+	}
+}
+
+// recycleTickerBuffer keeps a converted buffer for reuse by a later flush
+func (c *Task) recycleTickerBuffer(buf *bytes.Buffer) {
+	buf.Reset()
+
+	select {
+	case c.tickerFree <- buf:
+	default:
+	}
+}
+
+// runTickerWorker converts queued flushes in order until the queue is closed
+func (c *Task) runTickerWorker(queue <-chan *tickerFlush, done chan<- struct{}) {
+	defer close(done)
+
+	for job := range queue {
+		c.runTickerFlush(job)
+		<-c.tickerSlots
+	}
+
+	// Audio of a cancelled Flush that no later flush picked up
+	c.tickerLock.Lock()
+	carried := len(c.tickerCarry) > 0 && c.chunkMode()
+	c.tickerLock.Unlock()
+
+	if carried {
+		c.runTickerFlush(&tickerFlush{pending: c.freeTickerBuffer()})
+	}
+}
+
+// runTickerFlush converts a job and hands the outcome to its caller, or records
+// the error of a periodic flush. The audio of an abandoned Flush is carried over to
+// the next flush instead of being lost.
+func (c *Task) runTickerFlush(job *tickerFlush) {
+	c.tickerLock.Lock()
+
+	if job.abandoned {
+		c.carryTickerAudio(job)
+		c.tickerLock.Unlock()
+		return
+	}
+
+	job.started = true
+
+	if job.pending != nil && len(c.tickerCarry) > 0 {
+		carried := append(c.tickerCarry, job.pending.Bytes()...)
+		job.pending.Reset()
+		job.pending.Write(carried)
+		c.tickerCarry = nil
+	}
+
+	c.tickerLock.Unlock()
+
+	data, err := c.processTickerFlush(job)
+
+	if job.pending != nil {
+		c.recycleTickerBuffer(job.pending)
+	}
+
+	// Errors of a requested flush belong to its caller
+	if job.result != nil {
+		job.result <- tickerResult{data: data, err: err}
+	} else if err != nil {
+		c.tickerLock.Lock()
+		c.tickerErrs = append(c.tickerErrs, err)
+		c.tickerLock.Unlock()
+	}
+}
+
+// carryTickerAudio keeps the audio of a job that was not converted for the next
+// flush, and makes the next flush rewrite the output file (assumes lock is held)
+func (c *Task) carryTickerAudio(job *tickerFlush) {
+	if job.pending != nil {
+		c.tickerCarry = append(c.tickerCarry, job.pending.Bytes()...)
+	}
+
+	if job.recording != nil {
+		c.tickerWritten = 0
+	}
+}
+
+// processTickerFlush converts a detached batch of audio into chunks and the cumulative
+// output file, returning the converted chunk when one was produced
+func (c *Task) processTickerFlush(job *tickerFlush) ([]byte, error) {
+	ctx := job.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if c.Options.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Options.Timeout)
+
+		defer cancel()
+	}
+
+	var data []byte
+	var errs []error
+
+	// Deliver the audio written since the previous chunk
+	if job.pending != nil && job.pending.Len() > 0 {
+		chunk, err := c.emitTickerChunk(ctx, job)
+		data = chunk.Data
+		errs = append(errs, err)
+	}
+
+	// Rewrite the output file with the whole recording, since formats with
+	// headers (FLAC, WAV) cannot be appended to
+	if job.recording != nil {
+		if err := c.runSox(ctx, c.buildArgs("-", c.outputPath), newBytesReader(job.recording), nil); err != nil {
+			// Make the next flush rewrite it again
+			c.tickerLock.Lock()
+			c.tickerWritten = 0
+			c.tickerLock.Unlock()
+
+			errs = append(errs, err)
+		}
+	}
+
+	return data, errors.Join(errs...)
+}
+
+// emitTickerChunk converts a detached batch of audio, preceded by the configured overlap,
+// and delivers it to the output template, manifest and chunk handler. The chunk
+// progress only advances once sox converted the chunk; when it fails, the audio is
+// carried over to the next flush so the output has no gap.
+func (c *Task) emitTickerChunk(ctx context.Context, job *tickerFlush) (Chunk, error) {
+	c.tickerLock.Lock()
+	run, seq, flushed, tail := c.tickerRun, c.tickerSeq, c.tickerFlushed, c.tickerTail
+	c.tickerLock.Unlock()
+
+	window := job.pending.Bytes()
+	overlap := len(tail)
+
+	if overlap > 0 {
+		window = append(append(make([]byte, 0, overlap+len(window)), tail...), window...)
+	}
+
+	chunk := Chunk{
+		Seq:          seq + 1,
+		Offset:       c.inputDuration(flushed - overlap),
+		Duration:     c.inputDuration(len(window)),
+		Overlap:      c.inputDuration(overlap),
+		OverlapBytes: overlap,
+	}
+
+	err := c.convertTickerChunk(ctx, &chunk, window)
+
+	// A Reset during the conversion started a new run, which keeps its own progress
+	c.tickerLock.Lock()
+	if c.tickerRun == run {
+		if err != nil {
+			c.tickerCarry = append(append([]byte(nil), job.pending.Bytes()...), c.tickerCarry...)
+		} else {
+			c.advanceTickerChunk(chunk.Seq, flushed+job.pending.Len(), window)
+		}
+	}
+	c.tickerLock.Unlock()
+
+	if err != nil {
+		return chunk, err
+	}
+
+	if c.manifestPath != "" && chunk.Path != "" {
+		if err := c.appendManifest(chunk); err != nil {
+			return chunk, err
+		}
+	}
+
+	if c.tickerHandler != nil {
+		c.tickerHandler(chunk)
+	}
+
+	return chunk, nil
+}
+
+// convertTickerChunk converts a chunk window into chunk.Data, writing the chunk file
+// when an output template is set
+func (c *Task) convertTickerChunk(ctx context.Context, chunk *Chunk, window []byte) error {
+	input := newBytesReader(window)
+
+	if c.outputTemplate == "" {
+		output := &bytes.Buffer{}
+		if err := c.convertPipe(ctx, input, output); err != nil {
+			return err
+		}
+		chunk.Data = output.Bytes()
+
+		return nil
+	}
+
+	// Let sox write the file itself so formats with headers are finalized
+	chunk.Path = c.expandOutputTemplate(chunk.Seq, chunk.Offset, timeNow())
+
+	if err := os.MkdirAll(filepath.Dir(chunk.Path), 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	if err := c.runSox(ctx, c.buildArgs("-", chunk.Path), input, nil); err != nil {
+		return err
+	}
+
+	output, err := os.ReadFile(chunk.Path)
+	if err != nil {
+		return fmt.Errorf("failed to read chunk file: %w", err)
+	}
+	chunk.Data = output
+
+	return nil
+}
+
+// advanceTickerChunk records a converted chunk and keeps the tail of its window as
+// the overlap of the next chunk (assumes lock is held)
+func (c *Task) advanceTickerChunk(seq, flushed int, window []byte) {
+	c.tickerSeq = seq
+	c.tickerFlushed = flushed
+
+	if n := c.overlapBytes(); n > 0 {
+		if n > len(window) {
+			n = len(window)
+		}
+		c.tickerTail = append([]byte(nil), window[len(window)-n:]...)
+	}
+}